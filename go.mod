@@ -1,8 +1,15 @@
 module github.com/sinuspower/image-previewer
 
-go 1.15
+go 1.18
 
 require (
 	github.com/disintegration/imaging v1.6.2
 	github.com/stretchr/testify v1.6.1
 )
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/image v0.0.0-20191009234506-e7c1f5e7dbb8 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
+)
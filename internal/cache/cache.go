@@ -1,100 +1,226 @@
 package cache //nolint:golint,stylecheck
 
 import (
-	"crypto/sha1" //nolint:go-lint
-	"encoding/hex"
 	"errors"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type Key string
 
-type Cache interface {
-	Set(key Key, value interface{}) bool
-	Get(key Key) (interface{}, bool)
-	Clear() error
+// FileCache is disk storage with limited count of files.
+type FileCache interface {
+	Storage
+	Len() int
 	Cap() int
-	GetFile(string) ([]byte, bool, error)
-	PutFile(string, []byte) error
 }
 
+var (
+	ErrAlreadyInCache = errors.New("already in cache, rewritten")
+	ErrCachingPaused  = errors.New("caching is paused: low disk space")
+)
+
 type cacheItem struct {
-	key   Key
-	value interface{}
+	key        Key
+	path       string // original path or URL, empty for files stored by old versions
+	meta       Metadata
+	size       int64 // size of file data
+	lastAccess time.Time
+	hits       uint64
 }
 
+// lruCache is disk cache. Despite the name eviction order is decided by policy, LRU by default.
 type lruCache struct {
-	capacity int
-	path     string // path to cache dir in filesystem
-	queue    List
-	items    map[Key]*listItem
-	mutex    *sync.Mutex
+	path    string // path to cache dir in filesystem
+	items   *store[Key, *cacheItem]
+	lineage *lineage
+	size    int64 // total size of files data
+	stats   Stats // Items and Bytes are filled on request
+	hooks   Hooks
+	events  []hookEvent // changes made under mutex, hooks are called for them by unlock
+	paused  int32       // new files are not written if set, accessed atomically
+	mutex   *sync.Mutex
+}
+
+func NewFileCache(capacity int, path string) (FileCache, error) {
+	return newLRUCache(capacity, path, PolicyLRU)
+}
+
+// NewFileCacheWithPolicy creates cache with one of eviction policies listed in Policies.
+func NewFileCacheWithPolicy(capacity int, path string, policy string) (FileCache, error) {
+	lc, err := newLRUCache(capacity, path, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	return lc, nil
+}
+
+// NewPersistentCache creates cache which index is rebuilt from files already stored in path.
+// Files are ordered by modification time, corrupted and partially written ones are discarded.
+func NewPersistentCache(capacity int, path string) (FileCache, error) {
+	return NewPersistentCacheWithPolicy(capacity, path, PolicyLRU)
+}
+
+func NewPersistentCacheWithPolicy(capacity int, path string, policy string) (FileCache, error) {
+	lc, err := newLRUCache(capacity, path, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lc.restore(); err != nil {
+		return nil, err
+	}
+
+	return lc, nil
 }
 
-func NewCache(capacity int, path string) (Cache, error) {
+func newLRUCache(capacity int, path string, policy string) (*lruCache, error) {
+	items, err := newStore[Key, *cacheItem](capacity, policy)
+	if err != nil {
+		return nil, err
+	}
+
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		if err := os.Mkdir(path, 0700); err != nil {
 			return nil, err
 		}
 	}
 
-	return &lruCache{
-		capacity: capacity,
-		path:     path,
-		queue:    NewList(),
-		items:    make(map[Key]*listItem),
-		mutex:    &sync.Mutex{},
-	}, nil
+	lc := &lruCache{
+		path:    path,
+		items:   items,
+		lineage: newLineage(),
+		mutex:   &sync.Mutex{},
+	}
+	lc.SetHooks(Hooks{})
+
+	return lc, nil
 }
 
-func (lc *lruCache) Set(key Key, value interface{}) bool {
+// SetHooks replaces hooks of cache. Files of evicted keys are removed before OnEvict is called.
+func (lc *lruCache) SetHooks(hooks Hooks) {
+	onEvict := hooks.OnEvict
+	hooks.OnEvict = func(key Key, reason Reason) {
+		lc.removeFile(key)
+		if onEvict != nil {
+			onEvict(key, reason)
+		}
+	}
+
 	lc.mutex.Lock()
-	if itm, ok := lc.items[key]; ok { // refresh
-		refreshed := cacheItem{key, value}
-		itm.Value = refreshed
-		lc.queue.MoveToFront(lc.items[key])
-		lc.mutex.Unlock()
+	lc.hooks = hooks
+	lc.mutex.Unlock()
+}
+
+// unlock releases mutex and then calls hooks for changes made under it.
+func (lc *lruCache) unlock() {
+	events, hooks := lc.events, lc.hooks
+	lc.events = nil
+	lc.mutex.Unlock()
+
+	for _, event := range events {
+		hooks.call(event)
+	}
+}
+
+// set must be called with mutex locked, it reports whether key was in cache.
+func (lc *lruCache) set(item *cacheItem, reason Reason) bool {
+	key := item.key
+	lc.size += item.size
+	old, replaced, evicted := lc.items.set(key, item)
+	if replaced { // refresh
+		lc.size -= old.size
+		item.hits = old.hits
+		lc.events = append(lc.events, hookEvent{kind: hookInsert, key: key, reason: ReasonReplaced})
 
 		return true
 	}
 	// insert
-	lc.items[key] = lc.queue.PushFront(cacheItem{key, value})
-	if len(lc.items) > lc.capacity { // remove old record
-		old := lc.queue.Back()
-		key := old.Value.(cacheItem).key
-		delete(lc.items, key)
-		lc.queue.Remove(old)
-		// delete file if exists
-		fileName := lc.path + "/" + string(key)
-		if _, err := os.Stat(fileName); err == nil {
-			_ = os.Remove(fileName)
-		}
+	lc.events = append(lc.events, hookEvent{kind: hookInsert, key: key, reason: reason})
+	for _, old := range evicted { // remove old records
+		lc.evicted(old.value, ReasonCapacity)
 	}
-	lc.mutex.Unlock()
 
 	return false
 }
 
-func (lc *lruCache) Get(key Key) (interface{}, bool) {
+// evicted must be called with mutex locked for item already removed from index.
+// File of item is removed by hook.
+func (lc *lruCache) evicted(item *cacheItem, reason Reason) {
+	lc.size -= item.size
+	lc.stats.Evictions++
+	lc.lineage.unlink(item.key)
+	lc.events = append(lc.events, hookEvent{kind: hookEvict, key: item.key, reason: reason})
+}
+
+// evict removes up to n keys chosen by policy, returns count of removed keys.
+func (lc *lruCache) evict(n int, reason Reason) int {
 	lc.mutex.Lock()
-	if itm, ok := lc.items[key]; ok { // return value
-		lc.queue.MoveToFront(lc.items[key])
-		lc.mutex.Unlock()
+	defer lc.unlock()
 
-		return itm.Value.(cacheItem).value, true
+	evicted := 0
+	for ; evicted < n; evicted++ {
+		old, ok := lc.items.evict()
+		if !ok {
+			break
+		}
+		lc.evicted(old.value, reason)
 	}
-	lc.mutex.Unlock()
 
-	return nil, false
+	return evicted
 }
 
-func (lc *lruCache) Clear() error {
+func (lc *lruCache) Len() int {
 	lc.mutex.Lock()
 	defer lc.mutex.Unlock()
-	lc.queue = NewList()
-	lc.items = make(map[Key]*listItem)
+
+	return lc.items.len()
+}
+
+func (lc *lruCache) dir() string {
+	return lc.path
+}
+
+func (lc *lruCache) pause(paused bool) {
+	value := int32(0)
+	if paused {
+		value = 1
+	}
+	atomic.StoreInt32(&lc.paused, value)
+}
+
+// removeFile deletes file of evicted key unless the key is stored again since eviction.
+func (lc *lruCache) removeFile(key Key) {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+
+	if _, ok := lc.items.peek(key); !ok {
+		_ = os.Remove(lc.fileName(key))
+	}
+}
+
+// touch must be called with mutex locked, it records hit of item.
+func (lc *lruCache) touch(item *cacheItem) {
+	item.hits++
+	item.lastAccess = time.Now()
+	lc.stats.Hits++
+}
+
+// Clear drops all keys without calling hooks.
+func (lc *lruCache) Clear() error {
+	lc.mutex.Lock()
+	defer lc.unlock()
+	lc.events = nil
+	lc.items.reset()
+	lc.lineage = newLineage()
+	lc.size = 0
 	if err := os.RemoveAll(lc.path); err != nil {
 		return err
 	}
@@ -103,46 +229,366 @@ func (lc *lruCache) Clear() error {
 }
 
 func (lc *lruCache) Cap() int {
-	return lc.capacity
+	return lc.items.capacity
 }
 
-func (lc *lruCache) GetFile(path string) ([]byte, bool, error) {
-	key := getHash(path)
-	if _, ok := lc.Get(Key(key)); ok { // hashed filename is in cache
-		f, err := os.Open(lc.path + "/" + key)
-		if err != nil {
-			return nil, false, err
+func (lc *lruCache) GetFile(path string) ([]byte, Metadata, bool, error) {
+	key := getKey(path)
+	fileName := lc.fileName(key)
+
+	f, ok, err := lc.openFile(path, key)
+	if err != nil || !ok {
+		return nil, Metadata{}, false, err
+	}
+	defer f.Close()
+
+	raw, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, Metadata{}, false, err
+	}
+
+	record, err := decodeFile(raw)
+	if err != nil {
+		lc.remove(key, ReasonCorrupted)
+
+		return nil, Metadata{}, false, err
+	}
+
+	// modification time keeps access order for persistent cache
+	now := time.Now()
+	_ = os.Chtimes(fileName, now, now)
+
+	return record.data, record.Metadata, true, nil
+}
+
+// openFile opens file under mutex, so it can not be evicted or replaced between lookup and opening.
+// Opened file stays readable even if it is evicted later.
+func (lc *lruCache) openFile(path string, key Key) (*os.File, bool, error) {
+	lc.mutex.Lock()
+	defer lc.unlock()
+
+	item, ok := lc.items.get(key)
+	if !ok {
+		item, ok = lc.migrate(path, key)
+	}
+	if !ok {
+		lc.stats.Misses++
+
+		return nil, false, nil
+	}
+	lc.touch(item)
+
+	f, err := os.Open(lc.fileName(key))
+	if err != nil {
+		return nil, false, err
+	}
+
+	return f, true, nil
+}
+
+func (lc *lruCache) Stat(path string) (Metadata, bool) {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+
+	item, ok := lc.items.peek(getKey(path))
+	if !ok {
+		return Metadata{}, false
+	}
+
+	return item.meta, true
+}
+
+func (lc *lruCache) PutFile(path string, data []byte, meta Metadata) error {
+	return lc.putFile("", path, data, meta)
+}
+
+func (lc *lruCache) PutDerivedFile(source string, path string, data []byte, meta Metadata) error {
+	return lc.putFile(source, path, data, meta)
+}
+
+// putFile writes data with its description into temporary file first and then renames it,
+// so readers never see partially written files.
+func (lc *lruCache) putFile(source string, path string, data []byte, meta Metadata) error {
+	if atomic.LoadInt32(&lc.paused) != 0 {
+		return ErrCachingPaused
+	}
+
+	key := getKey(path)
+	meta = meta.withCreated(time.Now())
+	fileName := lc.fileName(key)
+	if err := os.MkdirAll(filepath.Dir(fileName), 0700); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(fileName), string(key)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	_, err = tmp.Write(encodeFile(fileRecord{Path: path, Source: source, Metadata: meta, data: data}))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpName)
+
+		return err
+	}
+
+	lc.mutex.Lock()
+	defer lc.unlock()
+
+	if err := os.Rename(tmpName, fileName); err != nil {
+		_ = os.Remove(tmpName)
+
+		return err
+	}
+
+	if legacy := getLegacyKey(path); lc.has(legacy) { // outdated copy
+		lc.removeLocked(legacy, ReasonReplaced)
+	}
+	wasInCache := lc.set(newCacheItem(key, path, meta, int64(len(data))), ReasonNew)
+	if source != "" {
+		lc.lineage.link(source, key)
+	}
+	if wasInCache {
+		return ErrAlreadyInCache
+	}
+
+	return nil
+}
+
+func (lc *lruCache) Purge(source string) (int, error) {
+	lc.mutex.Lock()
+	defer lc.unlock()
+
+	return lc.purge(source), nil
+}
+
+func (lc *lruCache) PurgePrefix(prefix string) (int, error) {
+	lc.mutex.Lock()
+	defer lc.unlock()
+
+	purged := 0
+	for _, path := range lc.paths(prefix) {
+		purged += lc.purge(path)
+	}
+
+	return purged, nil
+}
+
+func (lc *lruCache) sourceOf(path string) string {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+
+	return lc.lineage.source(getKey(path))
+}
+
+// purge must be called with mutex locked.
+func (lc *lruCache) purge(source string) int {
+	purged := 0
+	for _, key := range lc.lineage.purgeKeys(source) {
+		if lc.removeLocked(key, ReasonPurged) {
+			purged++
 		}
-		defer f.Close()
-		bytes, err := ioutil.ReadAll(f)
-		if err != nil {
-			return nil, false, err
+	}
+
+	return purged
+}
+
+// paths returns original paths of files starting with prefix and sources of previews starting with it,
+// as source may be already evicted. Files stored by old versions have no original path until they are requested again.
+func (lc *lruCache) paths(prefix string) []string {
+	paths := lc.lineage.sourcesWithPrefix(prefix)
+	for _, item := range lc.items.values {
+		if item.path != "" && strings.HasPrefix(item.path, prefix) {
+			paths = append(paths, item.path)
 		}
+	}
+
+	return paths
+}
+
+// remove deletes key from index and its file from filesystem.
+func (lc *lruCache) remove(key Key, reason Reason) {
+	lc.mutex.Lock()
+	defer lc.unlock()
+	lc.removeLocked(key, reason)
+}
 
-		return bytes, true, nil
+// removeLocked must be called with mutex locked, reports whether key was in cache.
+func (lc *lruCache) removeLocked(key Key, reason Reason) bool {
+	item, ok := lc.items.remove(key)
+	if ok {
+		lc.size -= item.size
+	}
+	lc.lineage.unlink(key)
+	_ = os.Remove(lc.fileName(key))
+	if ok {
+		lc.events = append(lc.events, hookEvent{kind: hookExpire, key: key, reason: reason})
 	}
 
-	return nil, false, nil
+	return ok
 }
 
-func (lc *lruCache) PutFile(path string, data []byte) error {
-	key := getHash(path)
-	fileName := lc.path + "/" + key
+// migrate must be called with mutex locked. It moves file stored under legacy SHA-1 key of path
+// to the new key, returns item of the file if there was such one.
+func (lc *lruCache) migrate(path string, key Key) (*cacheItem, bool) {
+	legacy := getLegacyKey(path)
+	if !lc.has(legacy) {
+		return nil, false
+	}
 
-	err := ioutil.WriteFile(fileName, data, 0600)
+	fileName := lc.fileName(key)
+	if err := os.MkdirAll(filepath.Dir(fileName), 0700); err != nil {
+		return nil, false
+	}
+	if err := os.Rename(lc.fileName(legacy), fileName); err != nil {
+		lc.removeLocked(legacy, ReasonCorrupted)
+
+		return nil, false
+	}
+
+	item, _ := lc.items.remove(legacy)
+	lc.size -= item.size
+	item.key, item.path = key, path
+	lc.set(item, ReasonRestored)
+
+	return item, true
+}
+
+// has must be called with mutex locked, it does not record access of key.
+func (lc *lruCache) has(key Key) bool {
+	_, ok := lc.items.peek(key)
+
+	return ok
+}
+
+func (lc *lruCache) fileName(key Key) string {
+	return fanOut(lc.path, key)
+}
+
+// restore fills index with files found in cache dir, the most recently used file goes to the front.
+// Original paths and sources of previews are restored from files.
+// Files of flat layout and files with legacy SHA-1 keys are moved to their fan-out subdirectories,
+// the latter get new keys when they are requested for the first time.
+func (lc *lruCache) restore() error {
+	type file struct {
+		name   string
+		info   os.FileInfo
+		record fileRecord
+		size   int64
+	}
+	files := []file{}
+
+	err := filepath.Walk(lc.path, func(fileName string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		if !isKey(info.Name()) && !isLegacyKey(info.Name()) {
+			_ = os.Remove(fileName)
+
+			return nil
+		}
+		record, err := readFile(fileName)
+		if err != nil {
+			_ = os.Remove(fileName)
+
+			return nil
+		}
+		size := int64(len(record.data))
+		record.data = nil // only size is needed
+		files = append(files, file{name: fileName, info: info, record: record, size: size})
+
+		return nil
+	})
 	if err != nil {
 		return err
 	}
 
-	if lc.Set(Key(key), 0) {
-		return errors.New("already in cache, rewritten")
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].info.ModTime().Before(files[j].info.ModTime())
+	})
+
+	lc.mutex.Lock()
+	defer lc.unlock()
+
+	for _, f := range files {
+		key := Key(f.info.Name())
+		if fileName := lc.fileName(key); fileName != f.name {
+			if err := os.MkdirAll(filepath.Dir(fileName), 0700); err != nil {
+				return err
+			}
+			if err := os.Rename(f.name, fileName); err != nil {
+				return err
+			}
+		}
+
+		item := newCacheItem(key, f.record.Path, f.record.Metadata.withCreated(f.info.ModTime()), f.size)
+		item.lastAccess = f.info.ModTime()
+		lc.set(item, ReasonRestored)
+		if f.record.Source != "" && lc.has(key) {
+			lc.lineage.link(f.record.Source, key)
+		}
 	}
 
 	return nil
 }
 
-func getHash(source string) string {
-	sha1Bytes := sha1.Sum([]byte(source)) //nolint:go-lint
+func (lc *lruCache) Entries(offset int, limit int) ([]Entry, int) {
+	lc.mutex.Lock()
+	entries := lc.entries()
+	lc.mutex.Unlock()
+
+	return page(entries, offset, limit)
+}
+
+// entries must be called with mutex locked.
+func (lc *lruCache) entries() []Entry {
+	entries := make([]Entry, 0, lc.items.len())
+	for _, item := range lc.items.values {
+		entries = append(entries, Entry{
+			Key:        item.key,
+			Path:       item.path,
+			Size:       item.size,
+			Created:    item.meta.Created,
+			LastAccess: item.lastAccess,
+			Hits:       item.hits,
+		})
+	}
+
+	return entries
+}
+
+func (lc *lruCache) Stats() Stats {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+
+	stats := lc.stats
+	stats.Items = lc.items.len()
+	stats.Bytes = lc.size
+
+	return stats
+}
+
+func newCacheItem(key Key, path string, meta Metadata, size int64) *cacheItem {
+	now := time.Now()
+
+	return &cacheItem{
+		key:        key,
+		path:       path,
+		meta:       meta.withCreated(now),
+		size:       size,
+		lastAccess: now,
+	}
+}
+
+func readFile(fileName string) (fileRecord, error) {
+	raw, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return fileRecord{}, err
+	}
 
-	return hex.EncodeToString(sha1Bytes[:])
+	return decodeFile(raw)
 }
@@ -1,8 +1,15 @@
 package cache //nolint:golint,stylecheck
 
 import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io/ioutil"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 	"testing"
 
@@ -11,8 +18,7 @@ import (
 
 func TestCache(t *testing.T) { //nolint:go-lint
 	t.Run("empty cache", func(t *testing.T) {
-		c, err := NewCache(10, "cache")
-		require.NoError(t, err)
+		c := NewCache[Key, int](10)
 
 		_, ok := c.Get("aaa")
 		require.False(t, ok)
@@ -20,13 +26,12 @@ func TestCache(t *testing.T) { //nolint:go-lint
 		_, ok = c.Get("bbb")
 		require.False(t, ok)
 
-		err = c.Clear()
-		require.NoError(t, err)
+		c.Clear()
+		require.Equal(t, 0, c.Len())
 	})
 
 	t.Run("simple", func(t *testing.T) {
-		c, err := NewCache(5, "cache")
-		require.NoError(t, err)
+		c := NewCache[Key, int](5)
 
 		wasInCache := c.Set("aaa", 100)
 		require.False(t, wasInCache)
@@ -51,23 +56,25 @@ func TestCache(t *testing.T) { //nolint:go-lint
 
 		val, ok = c.Get("ccc")
 		require.False(t, ok)
-		require.Nil(t, val)
+		require.Equal(t, 0, val)
 
-		err = c.Clear()
-		require.NoError(t, err)
+		require.True(t, c.Remove("bbb"))
+		require.False(t, c.Remove("bbb"))
+		require.Equal(t, 1, c.Len())
+
+		c.Clear()
 
 		val, ok = c.Get("aaa")
 		require.False(t, ok)
-		require.Equal(t, nil, val)
+		require.Equal(t, 0, val)
 
 		val, ok = c.Get("bbb")
 		require.False(t, ok)
-		require.Equal(t, nil, val)
+		require.Equal(t, 0, val)
 	})
 
 	t.Run("purge logic", func(t *testing.T) {
-		c, err := NewCache(3, "cache")
-		require.NoError(t, err)
+		c := NewCache[Key, int](3)
 
 		_ = c.Set("a", 100)
 		_ = c.Set("b", 200)
@@ -79,21 +86,18 @@ func TestCache(t *testing.T) { //nolint:go-lint
 
 		_ = c.Set("d", 400)
 
-		val, ok = c.Get("b")
+		_, ok = c.Get("b")
 		require.False(t, ok) // "b" has been pulled
-		require.Equal(t, nil, val)
 
 		_ = c.Set("e", 500)
 
-		val, ok = c.Get("c")
+		_, ok = c.Get("c")
 		require.False(t, ok) // "c" has been pulled
-		require.Equal(t, nil, val)
 
 		_ = c.Set("f", 600)
 
-		val, ok = c.Get("a")
+		_, ok = c.Get("a")
 		require.False(t, ok) // "a" has been pulled
-		require.Equal(t, nil, val)
 
 		// are "d", "e" and "f" in the place?
 		val, ok = c.Get("d")
@@ -107,15 +111,31 @@ func TestCache(t *testing.T) { //nolint:go-lint
 		val, ok = c.Get("f")
 		require.True(t, ok)
 		require.Equal(t, 600, val)
+		require.Equal(t, 3, c.Cap())
+	})
 
-		err = c.Clear()
+	t.Run("policy", func(t *testing.T) {
+		c, err := NewCacheWithPolicy[string, []byte](2, PolicyLFU)
 		require.NoError(t, err)
+
+		_ = c.Set("a", []byte("a"))
+		_ = c.Set("b", []byte("b"))
+		_, _ = c.Get("a") // "b" is the least frequently used now
+		_ = c.Set("c", []byte("c"))
+
+		_, ok := c.Get("b")
+		require.False(t, ok)
+		val, ok := c.Get("a")
+		require.True(t, ok)
+		require.Equal(t, []byte("a"), val)
+
+		_, err = NewCacheWithPolicy[string, int](2, "random")
+		require.EqualError(t, err, "unknown eviction policy: random")
 	})
 }
 
 func TestCacheMultithreading(t *testing.T) {
-	c, err := NewCache(10, "cache")
-	require.NoError(t, err)
+	c := NewCache[Key, int](10)
 
 	wg := &sync.WaitGroup{}
 	wg.Add(2)
@@ -135,6 +155,221 @@ func TestCacheMultithreading(t *testing.T) {
 	}()
 
 	wg.Wait()
-	err = c.Clear()
+	require.LessOrEqual(t, c.Len(), 10)
+}
+
+func TestCacheFilesMultithreading(t *testing.T) {
+	c, err := NewFileCache(5, "cache_files")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, c.Clear())
+	}()
+
+	// content depends on path only, so any successful read must return exactly it
+	content := func(path string) []byte {
+		return []byte(strings.Repeat(path, len(path)*100))
+	}
+
+	// require must not be called outside of test goroutine, so workers report failures by channel
+	failures := make(chan string, 8)
+	wg := &sync.WaitGroup{}
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(seed)) //nolint:gosec
+			for i := 0; i < 500; i++ {
+				path := "/image/" + strconv.Itoa(rnd.Intn(12)) + ".jpg"
+				if rnd.Intn(2) == 0 {
+					_ = c.PutFile(path, content(path), Metadata{}) // "already in cache" is not a failure here
+
+					continue
+				}
+
+				data, _, ok, err := c.GetFile(path)
+				if err != nil {
+					failures <- err.Error()
+
+					return
+				}
+				if ok && !bytes.Equal(content(path), data) {
+					failures <- "unexpected content of " + path
+
+					return
+				}
+			}
+		}(int64(w))
+	}
+	wg.Wait()
+	close(failures)
+
+	for failure := range failures {
+		t.Error(failure)
+	}
+
+	require.LessOrEqual(t, len(listFiles(t, "cache_files")), 5) // no temporary or evicted files left
+}
+
+func TestPersistentCache(t *testing.T) {
+	c, err := NewPersistentCache(3, "cache_persistent")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, c.Clear())
+	}()
+
+	require.NoError(t, c.PutFile("/image/a.jpg", []byte("aaa"), Metadata{}))
+	require.NoError(t, c.PutFile("/image/b.jpg", []byte("bbb"), Metadata{}))
+	require.NoError(t, c.PutFile("/image/c.jpg", []byte("ccc"), Metadata{}))
+
+	// damage one file and leave some garbage in cache dir
+	err = ioutil.WriteFile(fanOut("cache_persistent", getKey("/image/b.jpg")), []byte("IPC1bb"), 0600)
+	require.NoError(t, err)
+	err = ioutil.WriteFile("cache_persistent/garbage", []byte("garbage"), 0600)
+	require.NoError(t, err)
+
+	t.Run("restore", func(t *testing.T) {
+		c, err := NewPersistentCache(3, "cache_persistent")
+		require.NoError(t, err)
+
+		data, _, ok, err := c.GetFile("/image/a.jpg")
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, []byte("aaa"), data)
+
+		data, _, ok, err = c.GetFile("/image/c.jpg")
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, []byte("ccc"), data)
+
+		_, _, ok, err = c.GetFile("/image/b.jpg")
+		require.NoError(t, err)
+		require.False(t, ok) // corrupted file discarded
+
+		_, err = os.Stat(fanOut("cache_persistent", getKey("/image/b.jpg")))
+		require.True(t, os.IsNotExist(err))
+		_, err = os.Stat("cache_persistent/garbage")
+		require.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("capacity", func(t *testing.T) {
+		_, err := NewPersistentCache(1, "cache_persistent")
+		require.NoError(t, err)
+
+		require.Len(t, listFiles(t, "cache_persistent"), 1) // oldest files evicted
+	})
+}
+
+func TestCacheKeys(t *testing.T) {
+	preview := getKey("/fill/50/50/example.com/image.jpg")
+	source := getKey("http://example.com/image.jpg")
+	require.True(t, strings.HasPrefix(string(preview), "fill-"))
+	require.True(t, strings.HasPrefix(string(source), "src-"))
+	require.True(t, isKey(string(preview)))
+	require.False(t, isKey(string(getLegacyKey("/fill/50/50/example.com/image.jpg"))))
+
+	hash := strings.TrimPrefix(string(preview), "fill-")
+	require.Equal(t, filepath.Join("cache", hash[0:2], hash[2:4], string(preview)), fanOut("cache", preview))
+}
+
+func TestCacheMigration(t *testing.T) {
+	require.NoError(t, os.MkdirAll("cache_legacy/07", 0700))
+	defer os.RemoveAll("cache_legacy")
+
+	// flat layout: files named by SHA-1 of path, some of them already with new keys
+	write := func(fileName string, data string) {
+		require.NoError(t, ioutil.WriteFile(fileName, encodeFile(fileRecord{data: []byte(data)}), 0600))
+	}
+	write("cache_legacy/"+string(getLegacyKey("/image/a.jpg")), "aaa")
+	write("cache_legacy/07/"+string(getLegacyKey("/image/b.jpg")), "bbb") // old shard directory
+	write("cache_legacy/"+string(getKey("/image/c.jpg")), "ccc")
+
+	c, err := NewPersistentCache(5, "cache_legacy")
+	require.NoError(t, err)
+
+	for path, expected := range map[string]string{"/image/a.jpg": "aaa", "/image/b.jpg": "bbb", "/image/c.jpg": "ccc"} {
+		data, _, ok, err := c.GetFile(path)
+		require.NoError(t, err)
+		require.True(t, ok, path)
+		require.Equal(t, expected, string(data))
+	}
+
+	files := listFiles(t, "cache_legacy")
+	require.ElementsMatch(t, []string{
+		fanOut("cache_legacy", getKey("/image/a.jpg")),
+		fanOut("cache_legacy", getKey("/image/b.jpg")),
+		fanOut("cache_legacy", getKey("/image/c.jpg")),
+	}, files)
+
+	t.Run("rewritten", func(t *testing.T) {
+		write("cache_legacy/"+string(getLegacyKey("/image/d.jpg")), "old")
+		c, err := NewPersistentCache(5, "cache_legacy")
+		require.NoError(t, err)
+
+		require.NoError(t, c.PutFile("/image/d.jpg", []byte("new"), Metadata{}))
+		data, _, ok, err := c.GetFile("/image/d.jpg")
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, "new", string(data))
+		_, err = os.Stat(fanOut("cache_legacy", getLegacyKey("/image/d.jpg")))
+		require.True(t, os.IsNotExist(err))
+	})
+}
+
+func TestPersistentCacheMetadata(t *testing.T) {
+	c, err := NewPersistentCache(5, "cache_metadata")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, c.Clear())
+	}()
+
+	source := "http://example.com/image.jpg"
+	meta := Metadata{ContentType: "image/jpeg", ETag: `"abc"`, Width: 50, Height: 50}
+	require.NoError(t, c.PutFile(source, []byte("source"), Metadata{}))
+	require.NoError(t, c.(Purger).PutDerivedFile(source, "/fill/50/50/example.com/image.jpg", []byte("50"), meta))
+
+	// file of old format has data only
+	raw := make([]byte, fileHeaderSize+3)
+	copy(raw, fileMagicV1)
+	copy(raw[fileHeaderSize:], "old")
+	binary.BigEndian.PutUint32(raw[4:8], crc32.ChecksumIEEE([]byte("old")))
+	binary.BigEndian.PutUint64(raw[8:16], 3)
+	oldFile := fanOut("cache_metadata", getKey("/image/old.jpg"))
+	require.NoError(t, os.MkdirAll(filepath.Dir(oldFile), 0700))
+	require.NoError(t, ioutil.WriteFile(oldFile, raw, 0600))
+
+	c, err = NewPersistentCache(5, "cache_metadata")
+	require.NoError(t, err)
+
+	data, actual, ok, err := c.GetFile("/fill/50/50/example.com/image.jpg")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "50", string(data))
+	require.Equal(t, meta.ETag, actual.ETag)
+	require.Equal(t, meta.Width, actual.Width)
+
+	data, actual, ok, err = c.GetFile("/image/old.jpg")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "old", string(data))
+	require.Equal(t, Metadata{}, actual)
+
+	// paths and sources are restored, so previews still can be purged by source
+	purged, err := c.(Purger).Purge(source)
+	require.NoError(t, err)
+	require.Equal(t, 2, purged)
+}
+
+// listFiles returns names of all files in dir and its subdirectories.
+func listFiles(t *testing.T, dir string) []string {
+	files := []string{}
+	err := filepath.Walk(dir, func(fileName string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			files = append(files, fileName)
+		}
+
+		return err
+	})
 	require.NoError(t, err)
+
+	return files
 }
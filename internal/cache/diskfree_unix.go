@@ -0,0 +1,16 @@
+//go:build !windows
+// +build !windows
+
+package cache //nolint:golint,stylecheck
+
+import "syscall"
+
+// diskFree returns count of bytes available to unprivileged user on volume of path.
+func diskFree(path string) (uint64, error) {
+	stat := syscall.Statfs_t{}
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil //nolint:unconvert // types differ between systems
+}
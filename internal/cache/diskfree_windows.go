@@ -0,0 +1,8 @@
+//go:build windows
+// +build windows
+
+package cache //nolint:golint,stylecheck
+
+func diskFree(path string) (uint64, error) {
+	return 0, ErrNotSupported
+}
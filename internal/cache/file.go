@@ -0,0 +1,79 @@
+package cache //nolint:golint,stylecheck
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+)
+
+// Every cache file starts with a header: magic (4 bytes), CRC-32 of payload (4 bytes)
+// and payload length (8 bytes). It allows to detect corrupted or partially written files.
+// Payload of IPC2 files is length of JSON description (4 bytes), the description and data,
+// payload of IPC1 files is data only.
+const (
+	fileMagic       = "IPC2"
+	fileMagicV1     = "IPC1"
+	fileHeaderSize  = 16
+	fileMetaLenSize = 4
+)
+
+var ErrCorruptedFile = errors.New("corrupted cache file")
+
+// fileRecord is content of cache file. Path and source allow to restore index and lineage.
+type fileRecord struct {
+	Path     string   `json:"path,omitempty"`
+	Source   string   `json:"source,omitempty"`
+	Metadata Metadata `json:"metadata"`
+	data     []byte
+}
+
+func encodeFile(record fileRecord) []byte {
+	meta, _ := json.Marshal(record) // record has no values which can not be marshaled
+	payloadSize := fileMetaLenSize + len(meta) + len(record.data)
+
+	raw := make([]byte, fileHeaderSize+payloadSize)
+	copy(raw, fileMagic)
+	binary.BigEndian.PutUint64(raw[8:16], uint64(payloadSize))
+	payload := raw[fileHeaderSize:]
+	binary.BigEndian.PutUint32(payload[:fileMetaLenSize], uint32(len(meta)))
+	copy(payload[fileMetaLenSize:], meta)
+	copy(payload[fileMetaLenSize+len(meta):], record.data)
+	binary.BigEndian.PutUint32(raw[4:8], crc32.ChecksumIEEE(payload))
+
+	return raw
+}
+
+func decodeFile(raw []byte) (fileRecord, error) {
+	if len(raw) < fileHeaderSize || (string(raw[:4]) != fileMagic && string(raw[:4]) != fileMagicV1) {
+		return fileRecord{}, ErrCorruptedFile
+	}
+
+	payload := raw[fileHeaderSize:]
+	if binary.BigEndian.Uint64(raw[8:16]) != uint64(len(payload)) {
+		return fileRecord{}, ErrCorruptedFile
+	}
+	if binary.BigEndian.Uint32(raw[4:8]) != crc32.ChecksumIEEE(payload) {
+		return fileRecord{}, ErrCorruptedFile
+	}
+
+	if string(raw[:4]) == fileMagicV1 {
+		return fileRecord{data: payload}, nil
+	}
+
+	if len(payload) < fileMetaLenSize {
+		return fileRecord{}, ErrCorruptedFile
+	}
+	metaSize := int(binary.BigEndian.Uint32(payload[:fileMetaLenSize]))
+	if metaSize > len(payload)-fileMetaLenSize {
+		return fileRecord{}, ErrCorruptedFile
+	}
+
+	record := fileRecord{}
+	if err := json.Unmarshal(payload[fileMetaLenSize:fileMetaLenSize+metaSize], &record); err != nil {
+		return fileRecord{}, ErrCorruptedFile
+	}
+	record.data = payload[fileMetaLenSize+metaSize:]
+
+	return record, nil
+}
@@ -0,0 +1,57 @@
+package cache //nolint:golint,stylecheck
+
+// Reason tells why hook is called.
+type Reason string
+
+const (
+	ReasonNew       Reason = "new"       // inserted key was not in cache
+	ReasonReplaced  Reason = "replaced"  // key is stored again or its outdated copy is dropped
+	ReasonRestored  Reason = "restored"  // key is found on disk at startup
+	ReasonCapacity  Reason = "capacity"  // key is evicted by policy to make room
+	ReasonLowSpace  Reason = "low-space" // key is evicted by Watchdog to free disk space
+	ReasonPurged    Reason = "purged"    // key is removed by Purge or PurgePrefix
+	ReasonCorrupted Reason = "corrupted" // file of key is damaged
+)
+
+// Hooks are optional callbacks called after cache is changed. They are called without
+// cache lock held, so they may be slow or use the cache, but may see changes out of order.
+type Hooks struct {
+	OnInsert func(key Key, reason Reason)
+	OnEvict  func(key Key, reason Reason) // key is evicted to make room
+	OnExpire func(key Key, reason Reason) // key is removed as invalid
+}
+
+// Hooked is a storage which accepts hooks.
+type Hooked interface {
+	SetHooks(hooks Hooks)
+}
+
+type hookKind int
+
+const (
+	hookInsert hookKind = iota
+	hookEvict
+	hookExpire
+)
+
+type hookEvent struct {
+	kind   hookKind
+	key    Key
+	reason Reason
+}
+
+func (h Hooks) call(event hookEvent) {
+	var hook func(Key, Reason)
+	switch event.kind {
+	case hookInsert:
+		hook = h.OnInsert
+	case hookEvict:
+		hook = h.OnEvict
+	case hookExpire:
+		hook = h.OnExpire
+	}
+
+	if hook != nil {
+		hook(event.key, event.reason)
+	}
+}
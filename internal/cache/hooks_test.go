@@ -0,0 +1,92 @@
+package cache //nolint:golint,stylecheck
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHooks(t *testing.T) {
+	for _, config := range []StorageConfig{
+		{Capacity: 2, Path: "cache_hooks", Shards: 1},
+		{Capacity: 2, Path: "cache_hooks", Shards: 2},
+	} {
+		config := config
+		t.Run(fmt.Sprintf("%d shards", config.Shards), func(t *testing.T) {
+			s, err := NewStorage(config)
+			require.NoError(t, err)
+			defer func() {
+				require.NoError(t, s.Clear())
+			}()
+
+			events := []string{}
+			mutex := &sync.Mutex{}
+			record := func(kind string) func(Key, Reason) {
+				return func(key Key, reason Reason) {
+					// cache is not locked, so hook may use it
+					_ = s.(Inspector).Stats()
+					if kind == "evict" {
+						_, err := os.Stat(fanOut(config.Path, key))
+						require.True(t, os.IsNotExist(err)) // file is removed before hook
+					}
+
+					mutex.Lock()
+					defer mutex.Unlock()
+					events = append(events, kind+" "+string(reason)+" "+string(key))
+				}
+			}
+			s.(Hooked).SetHooks(Hooks{OnInsert: record("insert"), OnEvict: record("evict"), OnExpire: record("expire")})
+
+			take := func() []string {
+				mutex.Lock()
+				defer mutex.Unlock()
+				taken := events
+				events = []string{}
+
+				return taken
+			}
+
+			source := "http://example.com/image.jpg"
+			require.NoError(t, s.PutFile(source, []byte("source"), Metadata{}))
+			require.NoError(t, s.(Purger).PutDerivedFile(source, "/fill/50/50/"+source, []byte("50"), Metadata{}))
+			require.Equal(t, []string{
+				"insert new " + string(getKey(source)),
+				"insert new " + string(getKey("/fill/50/50/"+source)),
+			}, take())
+
+			require.True(t, errors.Is(s.PutFile(source, []byte("source"), Metadata{}), ErrAlreadyInCache))
+			require.Equal(t, []string{"insert replaced " + string(getKey(source))}, take())
+
+			purged, err := s.(Purger).Purge(source)
+			require.NoError(t, err)
+			require.Equal(t, 2, purged)
+			require.ElementsMatch(t, []string{
+				"expire purged " + string(getKey(source)),
+				"expire purged " + string(getKey("/fill/50/50/"+source)),
+			}, take())
+
+			if config.Shards > 1 {
+				return // capacity is split, so eviction order depends on shards
+			}
+			for _, path := range []string{"/image/a.jpg", "/image/b.jpg", "/image/c.jpg"} {
+				require.NoError(t, s.PutFile(path, []byte(path), Metadata{}))
+			}
+			require.Equal(t, []string{
+				"insert new " + string(getKey("/image/a.jpg")),
+				"insert new " + string(getKey("/image/b.jpg")),
+				"insert new " + string(getKey("/image/c.jpg")),
+				"evict capacity " + string(getKey("/image/a.jpg")),
+			}, take())
+
+			require.NoError(t, ioutil.WriteFile(fanOut(config.Path, getKey("/image/b.jpg")), []byte("IPC2"), 0600))
+			_, _, _, err = s.GetFile("/image/b.jpg")
+			require.True(t, errors.Is(err, ErrCorruptedFile))
+			require.Equal(t, []string{"expire corrupted " + string(getKey("/image/b.jpg"))}, take())
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package cache //nolint:golint,stylecheck
+
+import (
+	"sort"
+	"time"
+)
+
+// Inspector is a storage able to describe its content.
+type Inspector interface {
+	// Entries returns page of entries ordered by last access, the most recent first, and total count of entries.
+	Entries(offset int, limit int) ([]Entry, int)
+	Stats() Stats
+}
+
+type Entry struct {
+	Key        Key       `json:"key"`
+	Path       string    `json:"path"`
+	Size       int64     `json:"size"`
+	Created    time.Time `json:"created"`
+	LastAccess time.Time `json:"lastAccess"`
+	Hits       uint64    `json:"hits"`
+}
+
+type Stats struct {
+	Items     int    `json:"items"`
+	Bytes     int64  `json:"bytes"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+}
+
+func (s Stats) add(other Stats) Stats {
+	return Stats{
+		Items:     s.Items + other.Items,
+		Bytes:     s.Bytes + other.Bytes,
+		Hits:      s.Hits + other.Hits,
+		Misses:    s.Misses + other.Misses,
+		Evictions: s.Evictions + other.Evictions,
+	}
+}
+
+// page sorts entries and returns requested part of them.
+func page(entries []Entry, offset int, limit int) ([]Entry, int) {
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].LastAccess.Equal(entries[j].LastAccess) {
+			return entries[i].LastAccess.After(entries[j].LastAccess)
+		}
+
+		return entries[i].Key < entries[j].Key
+	})
+
+	total := len(entries)
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if limit < 0 || end > total {
+		end = total
+	}
+
+	return entries[offset:end], total
+}
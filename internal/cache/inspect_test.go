@@ -0,0 +1,48 @@
+package cache //nolint:golint,stylecheck
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspector(t *testing.T) {
+	configs := []StorageConfig{
+		{Backend: BackendDisk, Capacity: 2, Path: "cache_inspect"},
+		{Backend: BackendDisk, Capacity: 2, Path: "cache_inspect", Shards: 2},
+		{Backend: BackendMemory, Capacity: 2},
+	}
+
+	for _, config := range configs {
+		config := config
+		t.Run(config.Backend, func(t *testing.T) {
+			s, err := NewStorage(config)
+			require.NoError(t, err)
+			defer s.Clear()
+
+			inspector, ok := s.(Inspector)
+			require.True(t, ok)
+
+			require.NoError(t, s.PutFile("a", []byte("a"), Metadata{}))
+			require.NoError(t, s.PutFile("c", []byte("cc"), Metadata{})) // the other shard than "a"
+			_, _, ok, _ = s.GetFile("a")
+			require.True(t, ok)
+			_, _, ok, _ = s.GetFile("b")
+			require.False(t, ok)
+
+			entries, total := inspector.Entries(0, 10)
+			require.Equal(t, 2, total)
+			require.Equal(t, "a", entries[0].Path)
+			require.Equal(t, uint64(1), entries[0].Hits)
+			require.Equal(t, getKey("a"), entries[0].Key)
+			require.Equal(t, "c", entries[1].Path)
+			require.Equal(t, int64(2), entries[1].Size)
+
+			entries, total = inspector.Entries(5, 10)
+			require.Equal(t, 2, total)
+			require.Empty(t, entries)
+
+			require.Equal(t, Stats{Items: 2, Bytes: 3, Hits: 1, Misses: 1}, inspector.Stats())
+		})
+	}
+}
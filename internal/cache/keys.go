@@ -0,0 +1,76 @@
+package cache //nolint:golint,stylecheck
+
+import (
+	"crypto/sha1" //nolint:go-lint
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"strings"
+)
+
+// sourceNamespace is namespace of source images, they are cached by URL.
+const sourceNamespace = "src"
+
+// getKey returns key of cached file: namespace of path and SHA-256 of path,
+// i.e. fill-5e8c... for preview /fill/50/50/example.com/image.jpg and src-1f3a... for its source image.
+func getKey(path string) Key {
+	hash := sha256.Sum256([]byte(path))
+
+	return Key(namespace(path) + "-" + hex.EncodeToString(hash[:]))
+}
+
+// namespace returns the first segment of preview path or sourceNamespace for URLs and other paths.
+func namespace(path string) string {
+	if !strings.HasPrefix(path, "/") {
+		return sourceNamespace
+	}
+
+	segment := strings.SplitN(path[1:], "/", 2)[0]
+	if segment == "" || len(segment) > 16 {
+		return sourceNamespace
+	}
+	for _, r := range segment {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return sourceNamespace
+		}
+	}
+
+	return segment
+}
+
+func isKey(name string) bool {
+	i := strings.LastIndexByte(name, '-')
+	if i <= 0 {
+		return false
+	}
+	decoded, err := hex.DecodeString(name[i+1:])
+
+	return err == nil && len(decoded) == sha256.Size
+}
+
+// getLegacyKey returns key of files stored before namespaced keys, it is SHA-1 of path.
+func getLegacyKey(path string) Key {
+	hash := sha1.Sum([]byte(path)) //nolint:go-lint
+
+	return Key(hex.EncodeToString(hash[:]))
+}
+
+func isLegacyKey(name string) bool {
+	decoded, err := hex.DecodeString(name)
+
+	return err == nil && len(decoded) == sha1.Size
+}
+
+// fanOut returns file name of key in dir. Files are spread over two levels of subdirectories
+// named by the first bytes of hash, so no directory holds too many files.
+func fanOut(dir string, key Key) string {
+	hash := string(key)
+	if i := strings.LastIndexByte(hash, '-'); i >= 0 {
+		hash = hash[i+1:]
+	}
+	if len(hash) < 4 {
+		return filepath.Join(dir, string(key))
+	}
+
+	return filepath.Join(dir, hash[0:2], hash[2:4], string(key))
+}
@@ -1,32 +1,33 @@
 package cache //nolint:golint,stylecheck
 
-type List interface {
+// List is a doubly linked list of values of type T.
+type List[T any] interface {
 	Len() int
-	Front() *listItem
-	Back() *listItem
-	PushFront(v interface{}) *listItem
-	PushBack(v interface{}) *listItem
-	Remove(i *listItem)
-	MoveToFront(i *listItem)
+	Front() *listItem[T]
+	Back() *listItem[T]
+	PushFront(v T) *listItem[T]
+	PushBack(v T) *listItem[T]
+	Remove(i *listItem[T])
+	MoveToFront(i *listItem[T])
 }
 
-type listItem struct {
-	Value interface{}
-	Next  *listItem
-	Prev  *listItem
+type listItem[T any] struct {
+	Value T
+	Next  *listItem[T]
+	Prev  *listItem[T]
 }
 
-type list struct {
-	front *listItem
-	back  *listItem
+type list[T any] struct {
+	front *listItem[T]
+	back  *listItem[T]
 	len   int
 }
 
-func NewList() List {
-	return &list{}
+func NewList[T any]() List[T] {
+	return &list[T]{}
 }
 
-func (l *list) Len() int {
+func (l *list[T]) Len() int {
 	if l == nil {
 		return 0
 	}
@@ -34,16 +35,16 @@ func (l *list) Len() int {
 	return l.len
 }
 
-func (l *list) Front() *listItem {
+func (l *list[T]) Front() *listItem[T] {
 	return l.front
 }
 
-func (l *list) Back() *listItem {
+func (l *list[T]) Back() *listItem[T] {
 	return l.back
 }
 
-func (l *list) PushFront(v interface{}) *listItem { //nolint:dupl
-	itm := &listItem{
+func (l *list[T]) PushFront(v T) *listItem[T] { //nolint:dupl
+	itm := &listItem[T]{
 		Value: v,
 		Next:  nil,
 		Prev:  nil,
@@ -79,8 +80,8 @@ func (l *list) PushFront(v interface{}) *listItem { //nolint:dupl
 	return l.front
 }
 
-func (l *list) PushBack(v interface{}) *listItem { //nolint:dupl
-	itm := &listItem{
+func (l *list[T]) PushBack(v T) *listItem[T] { //nolint:dupl
+	itm := &listItem[T]{
 		Value: v,
 		Next:  nil,
 		Prev:  nil,
@@ -117,7 +118,7 @@ func (l *list) PushBack(v interface{}) *listItem { //nolint:dupl
 	return l.back
 }
 
-func (l *list) Remove(i *listItem) {
+func (l *list[T]) Remove(i *listItem[T]) {
 	if l.len == 0 { // empty list
 		return
 	}
@@ -154,7 +155,7 @@ func (l *list) Remove(i *listItem) {
 	l.len--
 }
 
-func (l *list) MoveToFront(i *listItem) {
+func (l *list[T]) MoveToFront(i *listItem[T]) {
 	if l.len == 0 { // empty list
 		return
 	}
@@ -8,7 +8,7 @@ import (
 
 func TestList(t *testing.T) { //nolint:go-lint
 	t.Run("empty list", func(t *testing.T) {
-		l := NewList()
+		l := NewList[int]()
 
 		require.Equal(t, l.Len(), 0)
 		require.Nil(t, l.Front())
@@ -16,7 +16,7 @@ func TestList(t *testing.T) { //nolint:go-lint
 	})
 
 	t.Run("complex", func(t *testing.T) {
-		l := NewList()
+		l := NewList[int]()
 
 		l.PushFront(10) // [10]
 		l.PushBack(20)  // [10, 20]
@@ -44,7 +44,7 @@ func TestList(t *testing.T) { //nolint:go-lint
 
 		elems := make([]int, 0, l.Len())
 		for i := l.Back(); i != nil; i = i.Next {
-			elems = append(elems, i.Value.(int))
+			elems = append(elems, i.Value)
 		}
 		require.Equal(t, []int{50, 30, 10, 40, 60, 80, 70}, elems)
 	})
@@ -52,7 +52,7 @@ func TestList(t *testing.T) { //nolint:go-lint
 	// new case
 	t.Run("remove all fronts", func(t *testing.T) { //nolint:go-lint
 		elems := []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"}
-		l := NewList()
+		l := NewList[string]()
 		for i, v := range elems {
 			if i < len(elems)/2 {
 				l.PushFront(v)
@@ -76,7 +76,7 @@ func TestList(t *testing.T) { //nolint:go-lint
 	// new case
 	t.Run("remove all backs", func(t *testing.T) { //nolint:go-lint
 		elems := []float64{0.0, 0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9}
-		l := NewList()
+		l := NewList[float64]()
 		for i, v := range elems {
 			if i < len(elems)/2 {
 				l.PushBack(v)
@@ -0,0 +1,284 @@
+package cache //nolint:golint,stylecheck
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrTooLarge = errors.New("image is larger than memory budget")
+
+// memoryStorage keeps images in RAM and evicts ones chosen by policy
+// when items count or total size of images exceeds the limits.
+type memoryStorage struct {
+	maxBytes int64 // 0 means unlimited
+	size     int64
+	onEvict  func(item memoryItem)
+	items    *store[Key, *memoryItem]
+	lineage  *lineage
+	stats    Stats // Items and Bytes are filled on request
+	mutex    *sync.Mutex
+}
+
+type memoryItem struct {
+	path       string
+	source     string // source image of preview, empty for source images
+	data       []byte
+	meta       Metadata
+	lastAccess time.Time
+	hits       uint64
+	promoted   bool // copy of image stored by cold tier, it is not written back on eviction
+}
+
+func NewMemoryStorage(capacity int) Storage {
+	ms, _ := newMemoryStorage(capacity, 0, PolicyLRU, nil)
+
+	return ms
+}
+
+// NewMemoryStorageWithPolicy creates storage with one of eviction policies listed in Policies.
+func NewMemoryStorageWithPolicy(capacity int, policy string) (Storage, error) {
+	ms, err := newMemoryStorage(capacity, 0, policy, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return ms, nil
+}
+
+// newMemoryStorage creates storage which calls onEvict (if not nil) for every evicted image.
+func newMemoryStorage(capacity int, maxBytes int64, policy string,
+	onEvict func(item memoryItem)) (*memoryStorage, error) {
+	items, err := newStore[Key, *memoryItem](capacity, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	return &memoryStorage{
+		maxBytes: maxBytes,
+		onEvict:  onEvict,
+		items:    items,
+		lineage:  newLineage(),
+		mutex:    &sync.Mutex{},
+	}, nil
+}
+
+func (ms *memoryStorage) GetFile(path string) ([]byte, Metadata, bool, error) {
+	key := getKey(path)
+
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	item, ok := ms.items.get(key)
+	if !ok {
+		ms.stats.Misses++
+
+		return nil, Metadata{}, false, nil
+	}
+
+	item.hits++
+	item.lastAccess = time.Now()
+	ms.stats.Hits++
+
+	return item.data, item.meta, true, nil
+}
+
+func (ms *memoryStorage) Stat(path string) (Metadata, bool) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	item, ok := ms.items.peek(getKey(path))
+	if !ok {
+		return Metadata{}, false
+	}
+
+	return item.meta, true
+}
+
+func (ms *memoryStorage) PutFile(path string, data []byte, meta Metadata) error {
+	return ms.PutDerivedFile("", path, data, meta)
+}
+
+func (ms *memoryStorage) PutDerivedFile(source string, path string, data []byte, meta Metadata) error {
+	return ms.put(source, path, data, meta, false)
+}
+
+// promote puts image read from cold tier, it is dropped on eviction as cold tier still has it.
+func (ms *memoryStorage) promote(source string, path string, data []byte, meta Metadata) error {
+	return ms.put(source, path, data, meta, true)
+}
+
+func (ms *memoryStorage) put(source string, path string, data []byte, meta Metadata, promoted bool) error {
+	key := getKey(path)
+	if ms.maxBytes > 0 && int64(len(data)) > ms.maxBytes {
+		ms.mutex.Lock()
+		ms.remove(key) // stored image is outdated even if the new one does not fit
+		ms.mutex.Unlock()
+
+		return ErrTooLarge
+	}
+
+	now := time.Now()
+	value := &memoryItem{
+		path:       path,
+		source:     source,
+		data:       make([]byte, len(data)), // caller may reuse its buffer
+		meta:       meta.withCreated(now),
+		lastAccess: now,
+		promoted:   promoted,
+	}
+	copy(value.data, data)
+
+	ms.mutex.Lock()
+	old, replaced, evictedPairs := ms.items.set(key, value)
+	if replaced {
+		ms.size -= int64(len(old.data))
+		value.hits = old.hits
+	}
+	ms.size += int64(len(data))
+	if source != "" {
+		ms.lineage.link(source, key)
+	}
+
+	for _, old := range evictedPairs {
+		ms.size -= int64(len(old.value.data))
+	}
+	for ms.maxBytes > 0 && ms.size > ms.maxBytes {
+		old, ok := ms.items.evict()
+		if !ok {
+			break
+		}
+		ms.size -= int64(len(old.value.data))
+		evictedPairs = append(evictedPairs, old)
+	}
+
+	evicted := make([]memoryItem, 0, len(evictedPairs))
+	for _, old := range evictedPairs {
+		evicted = append(evicted, *old.value)
+		ms.stats.Evictions++
+		ms.lineage.unlink(old.key)
+	}
+	ms.mutex.Unlock()
+
+	// callback may be slow (i.e. writes to disk), so it is called without lock
+	if ms.onEvict != nil {
+		for _, item := range evicted {
+			ms.onEvict(item)
+		}
+	}
+
+	return nil
+}
+
+func (ms *memoryStorage) Clear() error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+	ms.items.reset()
+	ms.lineage = newLineage()
+	ms.size = 0
+
+	return nil
+}
+
+func (ms *memoryStorage) Entries(offset int, limit int) ([]Entry, int) {
+	ms.mutex.Lock()
+	entries := make([]Entry, 0, ms.items.len())
+	for key, item := range ms.items.values {
+		entries = append(entries, Entry{
+			Key:        key,
+			Path:       item.path,
+			Size:       int64(len(item.data)),
+			Created:    item.meta.Created,
+			LastAccess: item.lastAccess,
+			Hits:       item.hits,
+		})
+	}
+	ms.mutex.Unlock()
+
+	return page(entries, offset, limit)
+}
+
+func (ms *memoryStorage) Stats() Stats {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	stats := ms.stats
+	stats.Items = ms.items.len()
+	stats.Bytes = ms.size
+
+	return stats
+}
+
+func (ms *memoryStorage) Purge(source string) (int, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	return ms.purge(source), nil
+}
+
+func (ms *memoryStorage) PurgePrefix(prefix string) (int, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	// previews may be stored without their source image, i.e. in hot tier
+	paths := ms.lineage.sourcesWithPrefix(prefix)
+	for _, item := range ms.items.values {
+		if strings.HasPrefix(item.path, prefix) {
+			paths = append(paths, item.path)
+		}
+	}
+
+	purged := 0
+	for _, path := range paths {
+		purged += ms.purge(path)
+	}
+
+	return purged, nil
+}
+
+func (ms *memoryStorage) sourceOf(path string) string {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	return ms.lineage.source(getKey(path))
+}
+
+// purge must be called with mutex locked.
+func (ms *memoryStorage) purge(source string) int {
+	purged := 0
+	for _, key := range ms.lineage.purgeKeys(source) {
+		if ms.remove(key) {
+			purged++
+		}
+	}
+
+	return purged
+}
+
+// remove must be called with mutex locked, it reports whether key was in storage.
+func (ms *memoryStorage) remove(key Key) bool {
+	ms.lineage.unlink(key)
+	item, ok := ms.items.remove(key)
+	if ok {
+		ms.size -= int64(len(item.data))
+	}
+
+	return ok
+}
+
+// drain removes all images from storage and returns them.
+func (ms *memoryStorage) drain() []memoryItem {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	items := make([]memoryItem, 0, ms.items.len())
+	for _, item := range ms.items.values {
+		items = append(items, *item)
+	}
+	ms.items.reset()
+	ms.lineage = newLineage()
+	ms.size = 0
+
+	return items
+}
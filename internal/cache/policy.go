@@ -0,0 +1,456 @@
+package cache //nolint:golint,stylecheck
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+)
+
+// Policy decides which keys stay in cache. It is not safe for concurrent use.
+type Policy[K comparable] interface {
+	// Get reports whether key is resident and records the access.
+	Get(key K) bool
+	// Add inserts key which is not resident and returns keys evicted to keep capacity.
+	Add(key K) []K
+	Remove(key K)
+	// Evict removes one key chosen by policy.
+	Evict() (K, bool)
+	Len() int
+}
+
+const (
+	PolicyLRU = "lru"
+	PolicyLFU = "lfu"
+	Policy2Q  = "2q"
+	PolicyARC = "arc"
+)
+
+var (
+	Policies         = []string{PolicyLRU, PolicyLFU, Policy2Q, PolicyARC}
+	ErrUnknownPolicy = errors.New("unknown eviction policy")
+)
+
+func NewPolicy[K comparable](name string, capacity int) (Policy[K], error) {
+	switch name {
+	case PolicyLRU, "":
+		return newLRUPolicy[K](capacity), nil
+	case PolicyLFU:
+		return newLFUPolicy[K](capacity), nil
+	case Policy2Q:
+		return new2QPolicy[K](capacity), nil
+	case PolicyARC:
+		return newARCPolicy[K](capacity), nil
+	default:
+		return nil, fmt.Errorf("%s: %s", ErrUnknownPolicy, name)
+	}
+}
+
+// keyList is a list of keys with access by key, front is the most recent one.
+type keyList[K comparable] struct {
+	queue List[K]
+	items map[K]*listItem[K]
+}
+
+func newKeyList[K comparable]() *keyList[K] {
+	return &keyList[K]{
+		queue: NewList[K](),
+		items: make(map[K]*listItem[K]),
+	}
+}
+
+func (kl *keyList[K]) has(key K) bool {
+	_, ok := kl.items[key]
+
+	return ok
+}
+
+func (kl *keyList[K]) len() int {
+	return len(kl.items)
+}
+
+func (kl *keyList[K]) pushFront(key K) {
+	kl.items[key] = kl.queue.PushFront(key)
+}
+
+func (kl *keyList[K]) moveToFront(key K) {
+	kl.queue.MoveToFront(kl.items[key])
+}
+
+func (kl *keyList[K]) remove(key K) bool {
+	itm, ok := kl.items[key]
+	if ok {
+		kl.queue.Remove(itm)
+		delete(kl.items, key)
+	}
+
+	return ok
+}
+
+func (kl *keyList[K]) popBack() (K, bool) {
+	itm := lastItem(kl.queue)
+	if itm == nil {
+		var zero K
+
+		return zero, false
+	}
+	key := itm.Value
+	kl.remove(key)
+
+	return key, true
+}
+
+// lastItem returns the least recently used item, single item of the list may be stored as "front" only.
+func lastItem[T any](l List[T]) *listItem[T] {
+	if l.Back() != nil {
+		return l.Back()
+	}
+
+	return l.Front()
+}
+
+// lruPolicy evicts the least recently used key.
+type lruPolicy[K comparable] struct {
+	capacity int
+	keys     *keyList[K]
+}
+
+func newLRUPolicy[K comparable](capacity int) *lruPolicy[K] {
+	return &lruPolicy[K]{capacity: capacity, keys: newKeyList[K]()}
+}
+
+func (p *lruPolicy[K]) Get(key K) bool {
+	if !p.keys.has(key) {
+		return false
+	}
+	p.keys.moveToFront(key)
+
+	return true
+}
+
+func (p *lruPolicy[K]) Add(key K) []K {
+	p.keys.pushFront(key)
+
+	evicted := []K{}
+	for p.keys.len() > p.capacity {
+		old, _ := p.keys.popBack()
+		evicted = append(evicted, old)
+	}
+
+	return evicted
+}
+
+func (p *lruPolicy[K]) Remove(key K) {
+	p.keys.remove(key)
+}
+
+func (p *lruPolicy[K]) Evict() (K, bool) {
+	return p.keys.popBack()
+}
+
+func (p *lruPolicy[K]) Len() int {
+	return p.keys.len()
+}
+
+// lfuPolicy evicts the least frequently used key, the oldest one among equals.
+type lfuPolicy[K comparable] struct {
+	capacity int
+	tick     uint64
+	entries  lfuHeap[K]
+	items    map[K]*lfuEntry[K]
+}
+
+type lfuEntry[K comparable] struct {
+	key   K
+	freq  uint64
+	tick  uint64 // time of the last access
+	index int
+}
+
+type lfuHeap[K comparable] []*lfuEntry[K]
+
+func (h lfuHeap[K]) Len() int { return len(h) }
+
+func (h lfuHeap[K]) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+
+	return h[i].tick < h[j].tick
+}
+
+func (h lfuHeap[K]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap[K]) Push(x interface{}) {
+	entry := x.(*lfuEntry[K])
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *lfuHeap[K]) Pop() interface{} {
+	old := *h
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+
+	return entry
+}
+
+func newLFUPolicy[K comparable](capacity int) *lfuPolicy[K] {
+	return &lfuPolicy[K]{capacity: capacity, items: make(map[K]*lfuEntry[K])}
+}
+
+func (p *lfuPolicy[K]) Get(key K) bool {
+	entry, ok := p.items[key]
+	if !ok {
+		return false
+	}
+	p.tick++
+	entry.freq++
+	entry.tick = p.tick
+	heap.Fix(&p.entries, entry.index)
+
+	return true
+}
+
+// Add evicts before insertion, otherwise a new key would be the first candidate for eviction.
+func (p *lfuPolicy[K]) Add(key K) []K {
+	evicted := []K{}
+	for len(p.items) >= p.capacity && len(p.items) > 0 {
+		old, _ := p.Evict()
+		evicted = append(evicted, old)
+	}
+
+	p.tick++
+	entry := &lfuEntry[K]{key: key, freq: 1, tick: p.tick}
+	heap.Push(&p.entries, entry)
+	p.items[key] = entry
+
+	return evicted
+}
+
+func (p *lfuPolicy[K]) Remove(key K) {
+	if entry, ok := p.items[key]; ok {
+		heap.Remove(&p.entries, entry.index)
+		delete(p.items, key)
+	}
+}
+
+func (p *lfuPolicy[K]) Evict() (K, bool) {
+	if len(p.entries) == 0 {
+		var zero K
+
+		return zero, false
+	}
+	entry := heap.Pop(&p.entries).(*lfuEntry[K])
+	delete(p.items, entry.key)
+
+	return entry.key, true
+}
+
+func (p *lfuPolicy[K]) Len() int {
+	return len(p.items)
+}
+
+// twoQueuePolicy is the full 2Q algorithm (T. Johnson, D. Shasha). New keys go to FIFO queue a1in,
+// keys evicted from it are remembered in ghost queue a1out. Only keys requested again
+// while being remembered get into the main LRU queue am, so a single scan can not flush it.
+type twoQueuePolicy[K comparable] struct {
+	capacity int
+	kin      int // max size of a1in
+	kout     int // max size of a1out
+	am       *keyList[K]
+	a1in     *keyList[K]
+	a1out    *keyList[K]
+}
+
+func new2QPolicy[K comparable](capacity int) *twoQueuePolicy[K] {
+	return &twoQueuePolicy[K]{
+		capacity: capacity,
+		kin:      max(1, capacity/4),
+		kout:     max(1, capacity/2),
+		am:       newKeyList[K](),
+		a1in:     newKeyList[K](),
+		a1out:    newKeyList[K](),
+	}
+}
+
+func (p *twoQueuePolicy[K]) Get(key K) bool {
+	if p.am.has(key) {
+		p.am.moveToFront(key)
+
+		return true
+	}
+
+	return p.a1in.has(key) // FIFO order is kept
+}
+
+func (p *twoQueuePolicy[K]) Add(key K) []K {
+	if p.a1out.remove(key) {
+		p.am.pushFront(key)
+	} else {
+		p.a1in.pushFront(key)
+	}
+
+	evicted := []K{}
+	for p.Len() > p.capacity {
+		old, _ := p.Evict()
+		evicted = append(evicted, old)
+	}
+
+	return evicted
+}
+
+func (p *twoQueuePolicy[K]) Remove(key K) {
+	_ = p.am.remove(key) || p.a1in.remove(key) || p.a1out.remove(key)
+}
+
+func (p *twoQueuePolicy[K]) Evict() (K, bool) {
+	if p.a1in.len() > p.kin || p.am.len() == 0 {
+		key, ok := p.a1in.popBack()
+		if ok {
+			p.a1out.pushFront(key)
+			if p.a1out.len() > p.kout {
+				p.a1out.popBack()
+			}
+		}
+
+		return key, ok
+	}
+
+	return p.am.popBack()
+}
+
+func (p *twoQueuePolicy[K]) Len() int {
+	return p.am.len() + p.a1in.len()
+}
+
+// arcPolicy is Adaptive Replacement Cache (N. Megiddo, D. Modha). Resident keys seen once live in t1,
+// seen at least twice - in t2. Ghost lists b1 and b2 remember keys evicted from t1 and t2
+// and adapt target size p of t1 between recency and frequency.
+type arcPolicy[K comparable] struct {
+	capacity int
+	p        int
+	t1       *keyList[K]
+	t2       *keyList[K]
+	b1       *keyList[K]
+	b2       *keyList[K]
+}
+
+func newARCPolicy[K comparable](capacity int) *arcPolicy[K] {
+	return &arcPolicy[K]{
+		capacity: capacity,
+		t1:       newKeyList[K](),
+		t2:       newKeyList[K](),
+		b1:       newKeyList[K](),
+		b2:       newKeyList[K](),
+	}
+}
+
+func (p *arcPolicy[K]) Get(key K) bool {
+	if p.t1.remove(key) || p.t2.remove(key) {
+		p.t2.pushFront(key)
+
+		return true
+	}
+
+	return false
+}
+
+func (p *arcPolicy[K]) Add(key K) []K {
+	evicted := []K{}
+	evict := func(inB2 bool) {
+		if old, ok := p.replace(inB2); ok {
+			evicted = append(evicted, old)
+		}
+	}
+
+	switch {
+	case p.b1.has(key):
+		p.p = min(p.capacity, p.p+max(1, p.b2.len()/p.b1.len()))
+		p.b1.remove(key)
+		if p.Len() >= p.capacity {
+			evict(false)
+		}
+		p.t2.pushFront(key)
+	case p.b2.has(key):
+		p.p = max(0, p.p-max(1, p.b1.len()/p.b2.len()))
+		p.b2.remove(key)
+		if p.Len() >= p.capacity {
+			evict(true)
+		}
+		p.t2.pushFront(key)
+	default:
+		l1 := p.t1.len() + p.b1.len()
+		total := l1 + p.t2.len() + p.b2.len()
+		switch {
+		case l1 >= p.capacity:
+			if p.t1.len() < p.capacity {
+				p.b1.popBack()
+				evict(false)
+			} else if old, ok := p.t1.popBack(); ok {
+				evicted = append(evicted, old)
+			}
+		case total >= p.capacity:
+			if total >= 2*p.capacity {
+				p.b2.popBack()
+			}
+			if p.Len() >= p.capacity {
+				evict(false)
+			}
+		}
+		p.t1.pushFront(key)
+	}
+
+	return evicted
+}
+
+// replace moves the least recently used key of t1 or t2 to corresponding ghost list.
+func (p *arcPolicy[K]) replace(inB2 bool) (K, bool) {
+	if p.t1.len() > 0 && (p.t1.len() > p.p || (inB2 && p.t1.len() == p.p) || p.t2.len() == 0) {
+		key, ok := p.t1.popBack()
+		if ok {
+			p.b1.pushFront(key)
+		}
+
+		return key, ok
+	}
+
+	key, ok := p.t2.popBack()
+	if ok {
+		p.b2.pushFront(key)
+	}
+
+	return key, ok
+}
+
+func (p *arcPolicy[K]) Remove(key K) {
+	_ = p.t1.remove(key) || p.t2.remove(key) || p.b1.remove(key) || p.b2.remove(key)
+}
+
+func (p *arcPolicy[K]) Evict() (K, bool) {
+	return p.replace(false)
+}
+
+func (p *arcPolicy[K]) Len() int {
+	return p.t1.len() + p.t2.len()
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,178 @@
+package cache //nolint:golint,stylecheck
+
+import (
+	"bufio"
+	"flag"
+	"math/rand"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Access trace for BenchmarkPolicies may be given as a file with one key per line:
+// go test -run none -bench Policies ./internal/cache -policy.trace=trace.txt -policy.capacity=1000.
+var (
+	traceFile     = flag.String("policy.trace", "", "file with access trace, one key per line")
+	traceCapacity = flag.Int("policy.capacity", 100, "cache capacity for access trace replay")
+)
+
+func TestPolicies(t *testing.T) {
+	for _, name := range Policies {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			p, err := NewPolicy[Key](name, 3)
+			require.NoError(t, err)
+
+			require.False(t, p.Get("a"))
+			require.Empty(t, p.Add("a"))
+			require.Empty(t, p.Add("b"))
+			require.Empty(t, p.Add("c"))
+			require.True(t, p.Get("a"))
+			require.Equal(t, 3, p.Len())
+
+			evicted := p.Add("d")
+			require.Len(t, evicted, 1)
+			require.NotEqual(t, Key("d"), evicted[0])
+			require.False(t, p.Get(evicted[0]))
+			require.True(t, p.Get("d"))
+			require.Equal(t, 3, p.Len())
+
+			p.Remove("d")
+			require.False(t, p.Get("d"))
+			require.Equal(t, 2, p.Len())
+
+			_, ok := p.Evict()
+			require.True(t, ok)
+			_, ok = p.Evict()
+			require.True(t, ok)
+			_, ok = p.Evict()
+			require.False(t, ok)
+			require.Equal(t, 0, p.Len())
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		_, err := NewPolicy[Key]("random", 3)
+		require.EqualError(t, err, "unknown eviction policy: random")
+	})
+}
+
+func TestPoliciesCapacity(t *testing.T) {
+	trace := scanTrace(20_000)
+
+	for _, name := range Policies {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			p, err := NewPolicy[Key](name, 50)
+			require.NoError(t, err)
+
+			resident := map[Key]bool{}
+			for _, key := range trace {
+				if p.Get(key) {
+					require.True(t, resident[key])
+
+					continue
+				}
+				require.False(t, resident[key])
+				resident[key] = true
+				for _, old := range p.Add(key) {
+					delete(resident, old)
+				}
+				require.LessOrEqual(t, p.Len(), 50)
+				require.Equal(t, len(resident), p.Len())
+			}
+		})
+	}
+}
+
+func TestPoliciesScanResistance(t *testing.T) {
+	trace := scanTrace(100_000)
+	ratios := map[string]float64{}
+
+	for _, name := range Policies {
+		p, err := NewPolicy[Key](name, 100)
+		require.NoError(t, err)
+		ratios[name] = hitRatio(p, trace)
+		t.Logf("%s hit ratio: %.3f", name, ratios[name])
+	}
+
+	require.Greater(t, ratios[Policy2Q], ratios[PolicyLRU])
+	require.Greater(t, ratios[PolicyARC], ratios[PolicyLRU])
+}
+
+// BenchmarkPolicies replays access trace and reports hit ratio of every policy.
+func BenchmarkPolicies(b *testing.B) {
+	trace := scanTrace(100_000)
+	if *traceFile != "" {
+		trace = readTrace(b, *traceFile)
+	}
+
+	for _, name := range Policies {
+		name := name
+		b.Run(name, func(b *testing.B) {
+			var ratio float64
+			for i := 0; i < b.N; i++ {
+				p, err := NewPolicy[Key](name, *traceCapacity)
+				require.NoError(b, err)
+				ratio = hitRatio(p, trace)
+			}
+			b.ReportMetric(ratio*100, "hit%")
+		})
+	}
+}
+
+func hitRatio(p Policy[Key], trace []Key) float64 {
+	hits := 0
+	for _, key := range trace {
+		if p.Get(key) {
+			hits++
+
+			continue
+		}
+		p.Add(key)
+	}
+
+	return float64(hits) / float64(len(trace))
+}
+
+// scanTrace returns skewed accesses to 1000 popular images
+// interrupted by crawler scans of rarely viewed ones.
+func scanTrace(length int) []Key {
+	rnd := rand.New(rand.NewSource(1))     //nolint:gosec
+	zipf := rand.NewZipf(rnd, 1.1, 1, 999) //nolint:gosec
+	trace := make([]Key, 0, length)
+	scanned := 0
+
+	for len(trace) < length {
+		if len(trace)%2000 == 1000 { // crawler requests 500 images nobody needs
+			for i := 0; i < 500 && len(trace) < length; i++ {
+				trace = append(trace, Key("scan-"+strconv.Itoa(scanned)))
+				scanned++
+			}
+
+			continue
+		}
+		trace = append(trace, Key("popular-"+strconv.FormatUint(zipf.Uint64(), 10)))
+	}
+
+	return trace
+}
+
+func readTrace(b *testing.B, fileName string) []Key {
+	f, err := os.Open(fileName)
+	require.NoError(b, err)
+	defer f.Close()
+
+	trace := []Key{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			trace = append(trace, Key(line))
+		}
+	}
+	require.NoError(b, scanner.Err())
+
+	return trace
+}
@@ -0,0 +1,81 @@
+package cache //nolint:golint,stylecheck
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrNotSupported = errors.New("operation is not supported by storage")
+
+// Purger is a storage which can drop source image together with previews derived from it.
+type Purger interface {
+	// PutDerivedFile stores preview and remembers source image it is made from.
+	PutDerivedFile(source string, path string, data []byte, meta Metadata) error
+	// Purge removes source image and its previews, returns count of removed entries.
+	Purge(source string) (int, error)
+	// PurgePrefix removes entries which original path starts with prefix and their previews.
+	PurgePrefix(prefix string) (int, error)
+}
+
+// lineage keeps source->previews relations of cached images. It is not safe for concurrent use.
+type lineage struct {
+	previews map[string]map[Key]bool // source URL -> keys of previews
+	sources  map[Key]string          // preview key -> source URL
+}
+
+func newLineage() *lineage {
+	return &lineage{
+		previews: make(map[string]map[Key]bool),
+		sources:  make(map[Key]string),
+	}
+}
+
+func (l *lineage) link(source string, key Key) {
+	l.unlink(key)
+	if l.previews[source] == nil {
+		l.previews[source] = make(map[Key]bool)
+	}
+	l.previews[source][key] = true
+	l.sources[key] = source
+}
+
+// unlink forgets removed preview. Previews of removed source are kept,
+// so they still can be purged by source URL.
+func (l *lineage) unlink(key Key) {
+	source, ok := l.sources[key]
+	if !ok {
+		return
+	}
+	delete(l.sources, key)
+	delete(l.previews[source], key)
+	if len(l.previews[source]) == 0 {
+		delete(l.previews, source)
+	}
+}
+
+// source returns source URL of preview, it is empty for source images and unknown previews.
+func (l *lineage) source(key Key) string {
+	return l.sources[key]
+}
+
+// sourcesWithPrefix returns source URLs starting with prefix, their images may be not stored.
+func (l *lineage) sourcesWithPrefix(prefix string) []string {
+	sources := []string{}
+	for source := range l.previews {
+		if strings.HasPrefix(source, prefix) {
+			sources = append(sources, source)
+		}
+	}
+
+	return sources
+}
+
+// purgeKeys returns key of source image and keys of its previews.
+func (l *lineage) purgeKeys(source string) []Key {
+	keys := []Key{getKey(source)}
+	for key := range l.previews[source] {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
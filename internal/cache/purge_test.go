@@ -0,0 +1,102 @@
+package cache //nolint:golint,stylecheck
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurge(t *testing.T) {
+	configs := []StorageConfig{
+		{Backend: BackendDisk, Capacity: 40, Path: "cache_purge"},
+		{Backend: BackendDisk, Capacity: 40, Path: "cache_purge", Shards: 4},
+		{Backend: BackendMemory, Capacity: 40},
+		{Backend: BackendDisk, Capacity: 40, Path: "cache_purge", HotBytes: 1024},
+	}
+
+	for _, config := range configs {
+		config := config
+		t.Run(config.Backend, func(t *testing.T) {
+			s, err := NewStorage(config)
+			require.NoError(t, err)
+			defer s.Clear()
+
+			purger, ok := s.(Purger)
+			require.True(t, ok)
+
+			for _, source := range []string{"http://a.com/1.jpg", "http://a.com/2.jpg", "http://b.com/1.jpg"} {
+				require.NoError(t, s.PutFile(source, []byte(source), Metadata{}))
+				require.NoError(t, purger.PutDerivedFile(source, "/fill/50/50/"+source, []byte("50"), Metadata{}))
+				require.NoError(t, purger.PutDerivedFile(source, "/fill/90/90/"+source, []byte("90"), Metadata{}))
+			}
+
+			purged, err := purger.Purge("http://a.com/1.jpg")
+			require.NoError(t, err)
+			require.Equal(t, 3, purged)
+
+			_, _, ok, _ = s.GetFile("/fill/50/50/http://a.com/1.jpg")
+			require.False(t, ok)
+			_, _, ok, _ = s.GetFile("/fill/50/50/http://a.com/2.jpg")
+			require.True(t, ok)
+
+			purged, err = purger.PurgePrefix("http://a.com/")
+			require.NoError(t, err)
+			require.Equal(t, 3, purged)
+
+			purged, err = purger.PurgePrefix("/fill/90/")
+			require.NoError(t, err)
+			require.Equal(t, 1, purged)
+
+			_, _, ok, _ = s.GetFile("http://b.com/1.jpg")
+			require.True(t, ok)
+			_, _, ok, _ = s.GetFile("/fill/50/50/http://b.com/1.jpg")
+			require.True(t, ok)
+		})
+	}
+}
+
+func TestPurgePrefixEvictedSource(t *testing.T) {
+	configs := []StorageConfig{
+		{Backend: BackendDisk, Capacity: 8, Path: "cache_purge"},
+		{Backend: BackendDisk, Capacity: 8, Path: "cache_purge", Shards: 4},
+		{Backend: BackendMemory, Capacity: 8},
+	}
+
+	for _, config := range configs {
+		config := config
+		t.Run(config.Backend, func(t *testing.T) {
+			s, err := NewStorage(config)
+			require.NoError(t, err)
+			defer s.Clear()
+
+			purger, ok := s.(Purger)
+			require.True(t, ok)
+
+			source := "http://a.com/1.jpg"
+			require.NoError(t, s.PutFile(source, []byte(source), Metadata{}))
+			require.NoError(t, purger.PutDerivedFile(source, "/fill/50/50/"+source, []byte("50"), Metadata{}))
+			require.NoError(t, purger.PutDerivedFile(source, "/fill/90/90/"+source, []byte("90"), Metadata{}))
+
+			// other images evict the source, previews are kept as recently used ones
+			for i := 0; i < 50; i++ {
+				require.NoError(t, s.PutFile(fmt.Sprintf("http://b.com/%d.jpg", i), []byte("b"), Metadata{}))
+				_, _, ok, _ = s.GetFile("/fill/50/50/" + source)
+				require.True(t, ok)
+				_, _, ok, _ = s.GetFile("/fill/90/90/" + source)
+				require.True(t, ok)
+			}
+			_, _, ok, _ = s.GetFile(source)
+			require.False(t, ok)
+
+			purged, err := purger.PurgePrefix("http://a.com/")
+			require.NoError(t, err)
+			require.Equal(t, 2, purged)
+
+			_, _, ok, _ = s.GetFile("/fill/50/50/" + source)
+			require.False(t, ok)
+			_, _, ok, _ = s.GetFile("/fill/90/90/" + source)
+			require.False(t, ok)
+		})
+	}
+}
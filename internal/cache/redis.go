@@ -0,0 +1,156 @@
+package cache //nolint:golint,stylecheck
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	redisTimeout  = 5 * time.Second
+	redisPoolSize = 8 // max connections, requests wait for a free one
+)
+
+var ErrRedis = errors.New("redis request failed")
+
+// redisStorage keeps images in Redis (or any server speaking RESP).
+// Eviction is left to the server's maxmemory policy.
+type redisStorage struct {
+	addr   string
+	prefix string          // namespace for keys of this service
+	slots  chan struct{}   // taken by requests in progress
+	idle   chan *redisConn // open connections which are not used by requests
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+}
+
+func NewRedisStorage(addr string, prefix string) (Storage, error) {
+	rs := &redisStorage{
+		addr:   addr,
+		prefix: prefix,
+		slots:  make(chan struct{}, redisPoolSize),
+		idle:   make(chan *redisConn, redisPoolSize),
+	}
+
+	if _, err := rs.do([]byte("PING")); err != nil {
+		return nil, err
+	}
+
+	return rs, nil
+}
+
+// GetFile reports values stored without metadata by old versions as missing, so they are rewritten.
+func (rs *redisStorage) GetFile(path string) ([]byte, Metadata, bool, error) {
+	reply, err := rs.do([]byte("GET"), rs.key(path))
+	if err != nil {
+		return nil, Metadata{}, false, err
+	}
+
+	raw, ok := reply.([]byte)
+	if !ok {
+		return nil, Metadata{}, false, fmt.Errorf("%s: unexpected GET reply %v", ErrRedis, reply)
+	}
+	if raw == nil {
+		return nil, Metadata{}, false, nil
+	}
+
+	record, err := decodeFile(raw)
+	if err != nil {
+		return nil, Metadata{}, false, nil
+	}
+
+	return record.data, record.Metadata, true, nil
+}
+
+// PutFile stores data in the same format as disk cache files.
+func (rs *redisStorage) PutFile(path string, data []byte, meta Metadata) error {
+	record := fileRecord{Path: path, Metadata: meta.withCreated(time.Now()), data: data}
+	_, err := rs.do([]byte("SET"), rs.key(path), encodeFile(record))
+
+	return err
+}
+
+// Clear removes only keys of this service.
+func (rs *redisStorage) Clear() error {
+	cursor := []byte("0")
+	for {
+		reply, err := rs.do([]byte("SCAN"), cursor, []byte("MATCH"), []byte(rs.prefix+"*"), []byte("COUNT"), []byte("100"))
+		if err != nil {
+			return err
+		}
+
+		parts, ok := reply.([]interface{})
+		if !ok || len(parts) != 2 {
+			return fmt.Errorf("%s: unexpected SCAN reply %v", ErrRedis, reply)
+		}
+		cursor, _ = parts[0].([]byte)
+		keys, _ := parts[1].([]interface{})
+
+		if len(keys) > 0 {
+			args := [][]byte{[]byte("DEL")}
+			for _, key := range keys {
+				if key, ok := key.([]byte); ok {
+					args = append(args, key)
+				}
+			}
+			if _, err := rs.do(args...); err != nil {
+				return err
+			}
+		}
+
+		if string(cursor) == "0" || cursor == nil {
+			return nil
+		}
+	}
+}
+
+func (rs *redisStorage) key(path string) []byte {
+	return []byte(rs.prefix + string(getKey(path)))
+}
+
+// do sends command by idle connection or a new one and reads reply,
+// connection is closed after network errors, so the next request dials again.
+func (rs *redisStorage) do(args ...[]byte) (interface{}, error) {
+	rs.slots <- struct{}{}
+	defer func() { <-rs.slots }()
+
+	var rc *redisConn
+	select {
+	case rc = <-rs.idle:
+	default:
+		conn, err := net.DialTimeout("tcp", rs.addr, redisTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ErrRedis, err)
+		}
+		rc = &redisConn{conn: conn, reader: bufio.NewReader(conn), writer: bufio.NewWriter(conn)}
+	}
+
+	_ = rc.conn.SetDeadline(time.Now().Add(redisTimeout))
+	reply, err := rc.roundTrip(args...)
+	if err != nil {
+		rc.conn.Close()
+
+		return nil, fmt.Errorf("%s: %w", ErrRedis, err)
+	}
+	rs.idle <- rc // never blocks: there are no more connections than slots
+
+	if e, ok := reply.(respError); ok {
+		return nil, fmt.Errorf("%s: %w", ErrRedis, e)
+	}
+
+	return reply, nil
+}
+
+func (rc *redisConn) roundTrip(args ...[]byte) (interface{}, error) {
+	if err := writeCommand(rc.writer, args...); err != nil {
+		return nil, err
+	}
+
+	return readReply(rc.reader)
+}
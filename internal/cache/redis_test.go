@@ -0,0 +1,194 @@
+package cache //nolint:golint,stylecheck
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// redisStandIn is an in-process RESP server supporting commands used by redisStorage.
+type redisStandIn struct {
+	listener net.Listener
+	data     map[string][]byte
+	mutex    *sync.Mutex
+}
+
+func newRedisStandIn(t *testing.T) *redisStandIn {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	r := &redisStandIn{
+		listener: listener,
+		data:     make(map[string][]byte),
+		mutex:    &sync.Mutex{},
+	}
+	go r.serve()
+
+	return r
+}
+
+func (r *redisStandIn) Addr() string {
+	return r.listener.Addr().String()
+}
+
+func (r *redisStandIn) Close() {
+	r.listener.Close()
+}
+
+func (r *redisStandIn) serve() {
+	for {
+		conn, err := r.listener.Accept()
+		if err != nil {
+			return
+		}
+		go r.handle(conn)
+	}
+}
+
+func (r *redisStandIn) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+
+	for {
+		request, err := readReply(reader)
+		if err != nil {
+			return
+		}
+		items, _ := request.([]interface{})
+		args := make([][]byte, len(items))
+		for i, item := range items {
+			args[i], _ = item.([]byte)
+		}
+		if err := r.exec(writer, args); err != nil {
+			return
+		}
+		if err := writer.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (r *redisStandIn) exec(w *bufio.Writer, args [][]byte) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(args) == 0 {
+		_, err := w.WriteString("-ERR empty command\r\n")
+
+		return err
+	}
+
+	switch strings.ToUpper(string(args[0])) {
+	case "PING":
+		_, err := w.WriteString("+PONG\r\n")
+
+		return err
+	case "GET":
+		return writeBulk(w, r.data[string(args[1])])
+	case "SET":
+		r.data[string(args[1])] = args[2]
+		_, err := w.WriteString("+OK\r\n")
+
+		return err
+	case "DEL":
+		for _, key := range args[1:] {
+			delete(r.data, string(key))
+		}
+		_, err := w.WriteString(":1\r\n")
+
+		return err
+	case "SCAN": // SCAN 0 MATCH prefix* COUNT n, everything is returned at once
+		prefix := strings.TrimSuffix(string(args[3]), "*")
+		keys := [][]byte{}
+		for key := range r.data {
+			if strings.HasPrefix(key, prefix) {
+				keys = append(keys, []byte(key))
+			}
+		}
+		if _, err := w.WriteString("*2\r\n"); err != nil {
+			return err
+		}
+		if err := writeBulk(w, []byte("0")); err != nil {
+			return err
+		}
+		if _, err := w.WriteString("*" + strconv.Itoa(len(keys)) + "\r\n"); err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err := writeBulk(w, key); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	default:
+		_, err := w.WriteString("-ERR unknown command\r\n")
+
+		return err
+	}
+}
+
+func TestRedisStorageReconnect(t *testing.T) {
+	redis := newRedisStandIn(t)
+	defer redis.Close()
+
+	s, err := NewRedisStorage(redis.Addr(), "test:")
+	require.NoError(t, err)
+	require.NoError(t, s.PutFile("/image/a.jpg", []byte("aaa"), Metadata{}))
+
+	// break current connection, the next request must dial again
+	rs := s.(*redisStorage)
+	rc := <-rs.idle
+	rc.conn.Close()
+	rs.idle <- rc
+
+	_, _, _, err = s.GetFile("/image/a.jpg")
+	require.Error(t, err)
+
+	data, _, ok, err := s.GetFile("/image/a.jpg")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, []byte("aaa"), data)
+}
+
+func TestRedisStoragePool(t *testing.T) {
+	redis := newRedisStandIn(t)
+	defer redis.Close()
+
+	s, err := NewRedisStorage(redis.Addr(), "test:")
+	require.NoError(t, err)
+
+	wg := sync.WaitGroup{}
+	failures := make(chan string, 50)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			path, value := fmt.Sprintf("/image/%d.jpg", i), []byte(strconv.Itoa(i))
+			if err := s.PutFile(path, value, Metadata{}); err != nil {
+				failures <- err.Error()
+
+				return
+			}
+			data, _, ok, err := s.GetFile(path)
+			if err != nil || !ok || string(data) != string(value) {
+				failures <- fmt.Sprintf("%s: got %q, %v, %v", path, data, ok, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(failures)
+	for failure := range failures {
+		t.Error(failure)
+	}
+
+	rs := s.(*redisStorage)
+	require.LessOrEqual(t, len(rs.idle), redisPoolSize) // connections are reused
+}
@@ -0,0 +1,121 @@
+package cache //nolint:golint,stylecheck
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Minimal implementation of REdis Serialization Protocol (RESP 2).
+
+var ErrProtocol = errors.New("redis protocol error")
+
+// respError is an error reply sent by server.
+type respError string
+
+func (e respError) Error() string {
+	return string(e)
+}
+
+// writeCommand writes command as an array of bulk strings.
+func writeCommand(w *bufio.Writer, args ...[]byte) error {
+	if _, err := fmt.Fprintf(w, "*%d\r\n", len(args)); err != nil {
+		return err
+	}
+	for _, arg := range args {
+		if err := writeBulk(w, arg); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+func writeBulk(w *bufio.Writer, data []byte) error {
+	if data == nil {
+		_, err := w.WriteString("$-1\r\n")
+
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "$%d\r\n", len(data)); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	_, err := w.WriteString("\r\n")
+
+	return err
+}
+
+// readReply returns string for simple strings, respError for errors, int64 for integers,
+// []byte (nil for null) for bulk strings and []interface{} for arrays.
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, fmt.Errorf("%s: empty reply", ErrProtocol)
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return respError(line[1:]), nil
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ErrProtocol, err)
+		}
+
+		return n, nil
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ErrProtocol, err)
+		}
+		if n < 0 {
+			return []byte(nil), nil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ErrProtocol, err)
+		}
+		if n < 0 {
+			return []interface{}(nil), nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+
+		return items, nil
+	default:
+		return nil, fmt.Errorf("%s: unexpected reply type %q", ErrProtocol, line[0])
+	}
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", fmt.Errorf("%s: bad line ending", ErrProtocol)
+	}
+
+	return line[:len(line)-2], nil
+}
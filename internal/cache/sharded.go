@@ -0,0 +1,269 @@
+package cache //nolint:golint,stylecheck
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// shardedCache partitions keys by hash across independent caches, each with its own mutex
+// and its own subdirectory, so requests for different keys do not wait for each other.
+type shardedCache struct {
+	capacity int
+	path     string
+	shards   []*lruCache
+}
+
+func NewShardedCache(capacity int, path string, shards int) (FileCache, error) {
+	sc, err := newShardedCache(StorageConfig{Capacity: capacity, Path: path, Shards: shards})
+	if err != nil {
+		return nil, err
+	}
+
+	return sc, nil
+}
+
+// newShardedCache splits capacity across shards, the first shards get the remainder.
+// Every shard gets at least one item, so capacity is raised to count of shards.
+func newShardedCache(config StorageConfig) (*shardedCache, error) {
+	if _, err := os.Stat(config.Path); os.IsNotExist(err) {
+		if err := os.Mkdir(config.Path, 0700); err != nil {
+			return nil, err
+		}
+	}
+
+	sc := &shardedCache{
+		path:   config.Path,
+		shards: make([]*lruCache, config.Shards),
+	}
+
+	for i := range sc.shards {
+		shardCapacity := config.Capacity / config.Shards
+		if i < config.Capacity%config.Shards || shardCapacity == 0 {
+			shardCapacity++
+		}
+		sc.capacity += shardCapacity
+
+		path := fmt.Sprintf("%s/shard-%02x", config.Path, i)
+		shard, err := newLRUCache(shardCapacity, path, config.Policy)
+		if err != nil {
+			return nil, err
+		}
+		if config.Persistent {
+			if err := shard.restore(); err != nil {
+				return nil, err
+			}
+		}
+		sc.shards[i] = shard
+	}
+	if config.Persistent {
+		stale, err := restoreStaleShards(config)
+		if err != nil {
+			return nil, err
+		}
+		if err := sc.rebalance(append(sc.shards, stale...)); err != nil {
+			return nil, err
+		}
+		for _, shard := range stale {
+			if err := os.RemoveAll(shard.path); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return sc, nil
+}
+
+// restoreStaleShards restores shards left after count of shards is decreased.
+func restoreStaleShards(config StorageConfig) ([]*lruCache, error) {
+	stale := []*lruCache{}
+	for i := config.Shards; ; i++ {
+		path := fmt.Sprintf("%s/shard-%02x", config.Path, i)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return stale, nil
+		}
+		shard, err := newLRUCache(config.Capacity, path, config.Policy)
+		if err != nil {
+			return nil, err
+		}
+		if err := shard.restore(); err != nil {
+			return nil, err
+		}
+		stale = append(stale, shard)
+	}
+}
+
+// rebalance moves restored images of given shards to their own shards, they are misplaced
+// if count of shards is changed. Images with legacy keys have no original path, so they stay where they are.
+func (sc *shardedCache) rebalance(shards []*lruCache) error {
+	for _, shard := range shards {
+		shard.mutex.Lock()
+		entries := shard.entries()
+		shard.mutex.Unlock()
+
+		for _, entry := range entries {
+			if entry.Path == "" {
+				continue
+			}
+			owner := sc.shard(entry.Path)
+			if owner == shard {
+				continue
+			}
+			record, err := readFile(shard.fileName(entry.Key))
+			if err == nil {
+				err = owner.putFile(record.Source, record.Path, record.data, record.Metadata)
+			}
+			if err != nil && !errors.Is(err, ErrAlreadyInCache) {
+				return err
+			}
+			shard.remove(entry.Key, ReasonReplaced)
+		}
+	}
+
+	return nil
+}
+
+func (sc *shardedCache) SetHooks(hooks Hooks) {
+	for _, shard := range sc.shards {
+		shard.SetHooks(hooks)
+	}
+}
+
+func (sc *shardedCache) Cap() int {
+	return sc.capacity
+}
+
+func (sc *shardedCache) GetFile(path string) ([]byte, Metadata, bool, error) {
+	return sc.shard(path).GetFile(path)
+}
+
+func (sc *shardedCache) Stat(path string) (Metadata, bool) {
+	return sc.shard(path).Stat(path)
+}
+
+func (sc *shardedCache) PutFile(path string, data []byte, meta Metadata) error {
+	return sc.shard(path).PutFile(path, data, meta)
+}
+
+func (sc *shardedCache) PutDerivedFile(source string, path string, data []byte, meta Metadata) error {
+	return sc.shard(path).PutDerivedFile(source, path, data, meta)
+}
+
+// Purge asks every shard: previews may be stored in other shards than their source.
+func (sc *shardedCache) Purge(source string) (int, error) {
+	purged := 0
+	for _, shard := range sc.shards {
+		n, err := shard.Purge(source)
+		if err != nil {
+			return purged, err
+		}
+		purged += n
+	}
+
+	return purged, nil
+}
+
+// sourceOf asks shard of preview, it keeps the link to source.
+func (sc *shardedCache) sourceOf(path string) string {
+	return sc.shard(path).sourceOf(path)
+}
+
+// PurgePrefix collects paths from every shard first, as previews of matched source may live in other shards.
+func (sc *shardedCache) PurgePrefix(prefix string) (int, error) {
+	paths := []string{}
+	for _, shard := range sc.shards {
+		shard.mutex.Lock()
+		paths = append(paths, shard.paths(prefix)...)
+		shard.mutex.Unlock()
+	}
+
+	purged := 0
+	for _, path := range paths {
+		n, err := sc.Purge(path)
+		if err != nil {
+			return purged, err
+		}
+		purged += n
+	}
+
+	return purged, nil
+}
+
+func (sc *shardedCache) Entries(offset int, limit int) ([]Entry, int) {
+	entries := []Entry{}
+	for _, shard := range sc.shards {
+		shard.mutex.Lock()
+		entries = append(entries, shard.entries()...)
+		shard.mutex.Unlock()
+	}
+
+	return page(entries, offset, limit)
+}
+
+func (sc *shardedCache) Stats() Stats {
+	stats := Stats{}
+	for _, shard := range sc.shards {
+		stats = stats.add(shard.Stats())
+	}
+
+	return stats
+}
+
+func (sc *shardedCache) Clear() error {
+	for _, shard := range sc.shards {
+		if err := shard.Clear(); err != nil {
+			return err
+		}
+	}
+
+	return os.RemoveAll(sc.path)
+}
+
+// evict takes keys from every shard in turn.
+func (sc *shardedCache) evict(n int, reason Reason) int {
+	evicted := 0
+	for evicted < n {
+		round := 0
+		for _, shard := range sc.shards {
+			if evicted+round < n {
+				round += shard.evict(1, reason)
+			}
+		}
+		if round == 0 {
+			break
+		}
+		evicted += round
+	}
+
+	return evicted
+}
+
+func (sc *shardedCache) Len() int {
+	n := 0
+	for _, shard := range sc.shards {
+		n += shard.Len()
+	}
+
+	return n
+}
+
+func (sc *shardedCache) dir() string {
+	return sc.path
+}
+
+func (sc *shardedCache) pause(paused bool) {
+	for _, shard := range sc.shards {
+		shard.pause(paused)
+	}
+}
+
+// shard chooses shard by the first bytes of SHA-256 hash in key of path,
+// so the layout is the same as long as count of shards is the same.
+func (sc *shardedCache) shard(path string) *lruCache {
+	key := string(getKey(path))
+	h, _ := strconv.ParseUint(key[strings.LastIndexByte(key, '-')+1:][:8], 16, 32)
+
+	return sc.shards[h%uint64(len(sc.shards))]
+}
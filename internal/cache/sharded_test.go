@@ -0,0 +1,146 @@
+package cache //nolint:golint,stylecheck
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardedCache(t *testing.T) {
+	c, err := NewShardedCache(16, "cache_sharded", 4)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, c.Clear())
+	}()
+	require.Equal(t, 16, c.Cap())
+
+	for i := 0; i < 100; i++ {
+		path := "/image/" + strconv.Itoa(i) + ".jpg"
+		require.NoError(t, c.PutFile(path, []byte(path), Metadata{}))
+
+		data, _, ok, err := c.GetFile(path)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, []byte(path), data)
+	}
+
+	stored := 0
+	for i := 0; i < 100; i++ {
+		if _, _, ok, _ := c.GetFile("/image/" + strconv.Itoa(i) + ".jpg"); ok {
+			stored++
+		}
+	}
+	require.LessOrEqual(t, stored, 16) // every shard keeps 4 images
+	require.Greater(t, stored, 0)
+
+	require.Equal(t, stored, c.Len())
+}
+
+func TestShardedCapacity(t *testing.T) {
+	for _, tc := range []struct {
+		capacity int
+		shards   int
+		expected []int
+	}{
+		{10, 4, []int{3, 3, 2, 2}},
+		{16, 4, []int{4, 4, 4, 4}},
+		{2, 4, []int{1, 1, 1, 1}}, // every shard keeps one image at least
+	} {
+		sc, err := newShardedCache(StorageConfig{Capacity: tc.capacity, Path: "cache_sharded", Shards: tc.shards})
+		require.NoError(t, err)
+
+		capacities := []int{}
+		total := 0
+		for _, shard := range sc.shards {
+			capacities = append(capacities, shard.Cap())
+			total += shard.Cap()
+		}
+		require.Equal(t, tc.expected, capacities)
+		require.Equal(t, total, sc.Cap())
+		require.NoError(t, sc.Clear())
+	}
+}
+
+func TestShardedRestore(t *testing.T) {
+	config := StorageConfig{Capacity: 100, Path: "cache_sharded", Shards: 4, Persistent: true}
+	sc, err := newShardedCache(config)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, sc.Clear())
+	}()
+
+	paths := []string{}
+	for i := 0; i < 20; i++ {
+		path := "/fill/50/50/example.com/" + strconv.Itoa(i) + ".jpg"
+		paths = append(paths, path)
+		require.NoError(t, sc.PutDerivedFile("http://example.com/"+strconv.Itoa(i)+".jpg", path, []byte(path),
+			Metadata{}))
+	}
+
+	// images are found after count of shards is changed
+	config.Shards = 3
+	sc, err = newShardedCache(config)
+	require.NoError(t, err)
+	require.Equal(t, 20, sc.Len())
+	for _, path := range paths {
+		data, _, ok, err := sc.GetFile(path)
+		require.NoError(t, err)
+		require.True(t, ok, path)
+		require.Equal(t, []byte(path), data)
+	}
+
+	purged, err := sc.Purge("http://example.com/0.jpg") // sources are moved along with previews
+	require.NoError(t, err)
+	require.Equal(t, 1, purged)
+
+	// fan-out directories of plain disk cache are not shards
+	require.DirExists(t, "cache_sharded/shard-02")
+	require.NoDirExists(t, "cache_sharded/02")
+}
+
+// BenchmarkCacheParallel compares throughput of single mutex and sharded caches.
+func BenchmarkCacheParallel(b *testing.B) {
+	lc, err := NewFileCache(10_000, "cache_bench")
+	require.NoError(b, err)
+	defer lc.Clear()
+
+	sc, err := NewShardedCache(10_000, "cache_bench_sharded", 32)
+	require.NoError(b, err)
+	defer sc.Clear()
+
+	paths := make([]string, 20_000)
+	for i := range paths {
+		paths[i] = "/image/" + strconv.Itoa(i) + ".jpg"
+	}
+
+	for _, bc := range []struct {
+		name  string
+		cache FileCache
+	}{
+		{"lruCache", lc},
+		{"shardedCache", sc},
+	} {
+		c := bc.cache
+		for _, path := range paths[:10_000] {
+			_ = c.PutFile(path, []byte(path), Metadata{})
+		}
+
+		b.Run(bc.name, func(b *testing.B) {
+			var seed uint64
+			b.RunParallel(func(pb *testing.PB) {
+				i := int(atomic.AddUint64(&seed, 7919))
+				for pb.Next() {
+					i++
+					path := paths[i%len(paths)]
+					if i%10 == 0 {
+						_ = c.PutFile(path, []byte(path), Metadata{})
+					} else {
+						_, _, _, _ = c.GetFile(path)
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package cache //nolint:golint,stylecheck
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Storage is a byte-blob storage backend for source images and previews.
+type Storage interface {
+	GetFile(string) ([]byte, Metadata, bool, error)
+	PutFile(string, []byte, Metadata) error
+	Clear() error
+}
+
+// Describer is a storage which returns metadata of stored image without reading the image.
+type Describer interface {
+	// Stat does not count as access to image.
+	Stat(path string) (Metadata, bool)
+}
+
+// Metadata describes stored image, so responses made from cache look like original ones.
+type Metadata struct {
+	ContentType  string    `json:"contentType,omitempty"`
+	ETag         string    `json:"etag,omitempty"`         // upstream ETag of source image, own one of preview
+	LastModified string    `json:"lastModified,omitempty"` // upstream Last-Modified of source image
+	Created      time.Time `json:"created"`                // storage sets current time if it is empty
+	Width        int       `json:"width,omitempty"`
+	Height       int       `json:"height,omitempty"`
+	// Headers are origin response headers passed to clients, they are replayed on cache hits
+	Headers map[string][]string `json:"headers,omitempty"`
+}
+
+// withCreated returns metadata with creation time set.
+func (m Metadata) withCreated(now time.Time) Metadata {
+	if m.Created.IsZero() {
+		m.Created = now
+	}
+
+	return m
+}
+
+const (
+	BackendDisk   = "disk"
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+var ErrUnknownBackend = errors.New("unknown cache backend")
+
+// StorageConfig describes storage backend chosen by configuration.
+type StorageConfig struct {
+	Backend    string
+	Capacity   int    // max items count, ignored by redis backend
+	Path       string // cache dir for disk backend
+	Persistent bool   // keep files between restarts for disk backend
+	Policy     string // eviction policy for disk and memory backends, LRU by default
+	Shards     int    // count of independent index partitions for disk backend
+	RedisAddr  string
+	HotBytes   int64 // budget of in-memory tier in front of backend, 0 disables it
+}
+
+func NewStorage(config StorageConfig) (Storage, error) {
+	storage, err := newBackend(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.HotBytes > 0 {
+		return NewTieredStorage(config.Capacity, config.HotBytes, storage), nil
+	}
+
+	return storage, nil
+}
+
+func newBackend(config StorageConfig) (Storage, error) {
+	switch config.Backend {
+	case BackendDisk, "":
+		if config.Shards > 1 {
+			sc, err := newShardedCache(config)
+			if err != nil {
+				return nil, err
+			}
+
+			return sc, nil
+		}
+		if config.Persistent {
+			return NewPersistentCacheWithPolicy(config.Capacity, config.Path, config.Policy)
+		}
+
+		return NewFileCacheWithPolicy(config.Capacity, config.Path, config.Policy)
+	case BackendMemory:
+		return NewMemoryStorageWithPolicy(config.Capacity, config.Policy)
+	case BackendRedis:
+		return NewRedisStorage(config.RedisAddr, "image-previewer:")
+	default:
+		return nil, fmt.Errorf("%s: %s", ErrUnknownBackend, config.Backend)
+	}
+}
@@ -0,0 +1,87 @@
+package cache //nolint:golint,stylecheck
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorages(t *testing.T) {
+	redis := newRedisStandIn(t)
+	defer redis.Close()
+
+	configs := []StorageConfig{
+		{Backend: BackendDisk, Capacity: 3, Path: "cache_storage"},
+		{Backend: BackendDisk, Capacity: 3, Path: "cache_storage", Policy: PolicyARC},
+		{Backend: BackendMemory, Capacity: 3},
+		{Backend: BackendMemory, Capacity: 3, Policy: Policy2Q},
+		{Backend: BackendRedis, RedisAddr: redis.Addr()},
+	}
+
+	for _, config := range configs {
+		config := config
+		t.Run(config.Backend+" "+config.Policy, func(t *testing.T) {
+			s, err := NewStorage(config)
+			require.NoError(t, err)
+
+			_, _, ok, err := s.GetFile("/image/a.jpg")
+			require.NoError(t, err)
+			require.False(t, ok)
+
+			created := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+			meta := Metadata{ContentType: "image/jpeg", ETag: `"abc"`, LastModified: "Thu, 01 Oct 2020 12:00:00 GMT",
+				Created: created, Width: 50, Height: 40}
+			require.NoError(t, s.PutFile("/image/a.jpg", []byte("aaa"), meta))
+			require.NoError(t, s.PutFile("/image/b.jpg", []byte("bbb"), Metadata{}))
+
+			data, actual, ok, err := s.GetFile("/image/a.jpg")
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, []byte("aaa"), data)
+			require.Equal(t, meta, actual)
+
+			if describer, ok := s.(Describer); ok {
+				actual, ok = describer.Stat("/image/a.jpg")
+				require.True(t, ok)
+				require.Equal(t, meta, actual)
+				_, ok = describer.Stat("/image/c.jpg")
+				require.False(t, ok)
+			}
+
+			data, actual, ok, err = s.GetFile("/image/b.jpg")
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, []byte("bbb"), data)
+			require.False(t, actual.Created.IsZero()) // set by storage
+
+			require.NoError(t, s.Clear())
+
+			_, _, ok, err = s.GetFile("/image/a.jpg")
+			require.NoError(t, err)
+			require.False(t, ok)
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		_, err := NewStorage(StorageConfig{Backend: "tape"})
+		require.EqualError(t, err, "unknown cache backend: tape")
+	})
+}
+
+func TestMemoryStorage(t *testing.T) {
+	s := NewMemoryStorage(2)
+
+	require.NoError(t, s.PutFile("a", []byte("a"), Metadata{}))
+	require.NoError(t, s.PutFile("b", []byte("b"), Metadata{}))
+	_, _, ok, _ := s.GetFile("a") // "b" is the least recently used now
+	require.True(t, ok)
+	require.NoError(t, s.PutFile("c", []byte("c"), Metadata{}))
+
+	_, _, ok, _ = s.GetFile("b")
+	require.False(t, ok)
+	_, _, ok, _ = s.GetFile("a")
+	require.True(t, ok)
+	_, _, ok, _ = s.GetFile("c")
+	require.True(t, ok)
+}
@@ -0,0 +1,174 @@
+package cache //nolint:golint,stylecheck
+
+import "sync"
+
+// Cache is an in-memory cache, eviction order is decided by policy. It is safe for concurrent use.
+type Cache[K comparable, V any] interface {
+	// Set stores value and reports whether key was in cache.
+	Set(key K, value V) bool
+	Get(key K) (V, bool)
+	// Remove reports whether key was in cache.
+	Remove(key K) bool
+	Len() int
+	Cap() int
+	Clear()
+}
+
+type syncCache[K comparable, V any] struct {
+	store *store[K, V]
+	mutex *sync.Mutex
+}
+
+func NewCache[K comparable, V any](capacity int) Cache[K, V] {
+	c, _ := NewCacheWithPolicy[K, V](capacity, PolicyLRU)
+
+	return c
+}
+
+// NewCacheWithPolicy creates cache with one of eviction policies listed in Policies.
+func NewCacheWithPolicy[K comparable, V any](capacity int, policy string) (Cache[K, V], error) {
+	s, err := newStore[K, V](capacity, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	return &syncCache[K, V]{store: s, mutex: &sync.Mutex{}}, nil
+}
+
+func (c *syncCache[K, V]) Set(key K, value V) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	_, replaced, _ := c.store.set(key, value)
+
+	return replaced
+}
+
+func (c *syncCache[K, V]) Get(key K) (V, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.store.get(key)
+}
+
+func (c *syncCache[K, V]) Remove(key K) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	_, ok := c.store.remove(key)
+
+	return ok
+}
+
+func (c *syncCache[K, V]) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.store.len()
+}
+
+func (c *syncCache[K, V]) Cap() int {
+	return c.store.capacity
+}
+
+func (c *syncCache[K, V]) Clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.store.reset()
+}
+
+// pair is a key with its value, i.e. evicted one.
+type pair[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// store is a map which keys are evicted by policy. It is not safe for concurrent use,
+// evicted values are returned to caller, so storages can release resources they hold.
+type store[K comparable, V any] struct {
+	capacity int
+	policy   string // name of eviction policy
+	order    Policy[K]
+	values   map[K]V
+}
+
+func newStore[K comparable, V any](capacity int, policy string) (*store[K, V], error) {
+	order, err := NewPolicy[K](policy, capacity)
+	if err != nil {
+		return nil, err
+	}
+
+	return &store[K, V]{
+		capacity: capacity,
+		policy:   policy,
+		order:    order,
+		values:   make(map[K]V),
+	}, nil
+}
+
+// get returns value of key and records the access.
+func (s *store[K, V]) get(key K) (V, bool) {
+	if !s.order.Get(key) {
+		var zero V
+
+		return zero, false
+	}
+
+	return s.values[key], true
+}
+
+// peek returns value of key without recording the access.
+func (s *store[K, V]) peek(key K) (V, bool) {
+	value, ok := s.values[key]
+
+	return value, ok
+}
+
+// set stores value of key. It returns previous value if key was in store,
+// otherwise keys evicted to keep capacity with their values.
+func (s *store[K, V]) set(key K, value V) (V, bool, []pair[K, V]) {
+	if s.order.Get(key) { // refresh
+		old := s.values[key]
+		s.values[key] = value
+
+		return old, true, nil
+	}
+
+	s.values[key] = value
+	evicted := []pair[K, V]{}
+	for _, old := range s.order.Add(key) {
+		evicted = append(evicted, pair[K, V]{key: old, value: s.values[old]})
+		delete(s.values, old)
+	}
+
+	var zero V
+
+	return zero, false, evicted
+}
+
+func (s *store[K, V]) remove(key K) (V, bool) {
+	value, ok := s.values[key]
+	s.order.Remove(key)
+	delete(s.values, key)
+
+	return value, ok
+}
+
+// evict removes one key chosen by policy.
+func (s *store[K, V]) evict() (pair[K, V], bool) {
+	key, ok := s.order.Evict()
+	if !ok {
+		return pair[K, V]{}, false
+	}
+	value := s.values[key]
+	delete(s.values, key)
+
+	return pair[K, V]{key: key, value: value}, true
+}
+
+func (s *store[K, V]) len() int {
+	return len(s.values)
+}
+
+func (s *store[K, V]) reset() {
+	s.order, _ = NewPolicy[K](s.policy, s.capacity) // policy name is checked in constructor
+	s.values = make(map[K]V)
+}
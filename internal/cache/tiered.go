@@ -0,0 +1,197 @@
+package cache //nolint:golint,stylecheck
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// Tiered is a storage with hot in-memory tier in front of cold one.
+type Tiered interface {
+	Storage
+	TieredStats() TieredStats
+	Flush() error
+}
+
+type TierStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+type TieredStats struct {
+	Hot  TierStats
+	Cold TierStats
+}
+
+// sourcer is a storage which knows source image of stored preview.
+type sourcer interface {
+	sourceOf(path string) string
+}
+
+// tieredStorage puts new images into hot tier. Images evicted from hot tier are demoted
+// into cold one, images found in cold tier are promoted into hot one.
+type tieredStorage struct {
+	hot        *memoryStorage
+	cold       Storage
+	hotHits    uint64
+	hotMisses  uint64
+	coldHits   uint64
+	coldMisses uint64
+}
+
+// NewTieredStorage creates hot tier limited by capacity items and maxBytes total size of images.
+func NewTieredStorage(capacity int, maxBytes int64, cold Storage) Tiered {
+	ts := &tieredStorage{cold: cold}
+	ts.hot, _ = newMemoryStorage(capacity, maxBytes, PolicyLRU, ts.demote)
+
+	return ts
+}
+
+func (ts *tieredStorage) GetFile(path string) ([]byte, Metadata, bool, error) {
+	if data, meta, ok, _ := ts.hot.GetFile(path); ok {
+		atomic.AddUint64(&ts.hotHits, 1)
+
+		return data, meta, true, nil
+	}
+	atomic.AddUint64(&ts.hotMisses, 1)
+
+	data, meta, ok, err := ts.cold.GetFile(path)
+	if err != nil || !ok {
+		atomic.AddUint64(&ts.coldMisses, 1)
+
+		return nil, Metadata{}, false, err
+	}
+	atomic.AddUint64(&ts.coldHits, 1)
+
+	// promote with source, so the preview is still purged by it; too large images stay in cold tier only
+	source := ""
+	if s, ok := ts.cold.(sourcer); ok {
+		source = s.sourceOf(path)
+	}
+	_ = ts.hot.promote(source, path, data, meta)
+
+	return data, meta, true, nil
+}
+
+// Stat looks into cold tier if it is able to describe images too.
+func (ts *tieredStorage) Stat(path string) (Metadata, bool) {
+	if meta, ok := ts.hot.Stat(path); ok {
+		return meta, true
+	}
+	if describer, ok := ts.cold.(Describer); ok {
+		return describer.Stat(path)
+	}
+
+	return Metadata{}, false
+}
+
+func (ts *tieredStorage) PutFile(path string, data []byte, meta Metadata) error {
+	return ts.PutDerivedFile("", path, data, meta)
+}
+
+// PutDerivedFile puts too large images into cold tier only, hot tier drops their previous versions.
+func (ts *tieredStorage) PutDerivedFile(source string, path string, data []byte, meta Metadata) error {
+	err := ts.hot.PutDerivedFile(source, path, data, meta)
+	if errors.Is(err, ErrTooLarge) {
+		return ts.putCold(memoryItem{path: path, source: source, data: data, meta: meta})
+	}
+
+	return err
+}
+
+func (ts *tieredStorage) Clear() error {
+	if err := ts.hot.Clear(); err != nil {
+		return err
+	}
+
+	return ts.cold.Clear()
+}
+
+func (ts *tieredStorage) TieredStats() TieredStats {
+	return TieredStats{
+		Hot: TierStats{
+			Hits:   atomic.LoadUint64(&ts.hotHits),
+			Misses: atomic.LoadUint64(&ts.hotMisses),
+		},
+		Cold: TierStats{
+			Hits:   atomic.LoadUint64(&ts.coldHits),
+			Misses: atomic.LoadUint64(&ts.coldMisses),
+		},
+	}
+}
+
+// Flush demotes all images from hot tier, i.e. before shutdown of persistent cache.
+// Promoted images are dropped, cold tier already has them.
+func (ts *tieredStorage) Flush() error {
+	var err error
+	for _, item := range ts.hot.drain() {
+		if item.promoted {
+			continue
+		}
+		e := ts.putCold(item)
+		if e != nil && !errors.Is(e, ErrAlreadyInCache) && err == nil {
+			err = e
+		}
+	}
+
+	return err
+}
+
+// Entries lists images of both tiers, image may be listed twice if it is promoted from cold tier.
+func (ts *tieredStorage) Entries(offset int, limit int) ([]Entry, int) {
+	entries, _ := ts.hot.Entries(0, -1)
+	if inspector, ok := ts.cold.(Inspector); ok {
+		coldEntries, _ := inspector.Entries(0, -1)
+		entries = append(entries, coldEntries...)
+	}
+
+	return page(entries, offset, limit)
+}
+
+// Stats sums stats of both tiers, so request missed by hot tier is counted by cold tier again.
+func (ts *tieredStorage) Stats() Stats {
+	stats := ts.hot.Stats()
+	if inspector, ok := ts.cold.(Inspector); ok {
+		stats = stats.add(inspector.Stats())
+	}
+
+	return stats
+}
+
+// Purge removes images from both tiers, so image promoted from cold tier is counted twice.
+func (ts *tieredStorage) Purge(source string) (int, error) {
+	purger, ok := ts.cold.(Purger)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+	hot, _ := ts.hot.Purge(source)
+	cold, err := purger.Purge(source)
+
+	return hot + cold, err
+}
+
+func (ts *tieredStorage) PurgePrefix(prefix string) (int, error) {
+	purger, ok := ts.cold.(Purger)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+	hot, _ := ts.hot.PurgePrefix(prefix)
+	cold, err := purger.PurgePrefix(prefix)
+
+	return hot + cold, err
+}
+
+// demote skips promoted images, so reading working set larger than hot tier does not rewrite cold one.
+func (ts *tieredStorage) demote(item memoryItem) {
+	if item.promoted {
+		return
+	}
+	_ = ts.putCold(item) // cold tier may already have the image
+}
+
+func (ts *tieredStorage) putCold(item memoryItem) error {
+	if purger, ok := ts.cold.(Purger); ok && item.source != "" {
+		return purger.PutDerivedFile(item.source, item.path, item.data, item.meta)
+	}
+
+	return ts.cold.PutFile(item.path, item.data, item.meta)
+}
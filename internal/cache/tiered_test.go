@@ -0,0 +1,153 @@
+package cache //nolint:golint,stylecheck
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTieredStorage(t *testing.T) {
+	cold := NewMemoryStorage(10)
+	ts := NewTieredStorage(10, 6, cold) // hot tier keeps two 3-byte images only
+
+	require.NoError(t, ts.PutFile("a", []byte("aaa"), Metadata{}))
+	require.NoError(t, ts.PutFile("b", []byte("bbb"), Metadata{}))
+
+	_, _, ok, _ := cold.GetFile("a")
+	require.False(t, ok) // new images go to hot tier
+
+	data, _, ok, err := ts.GetFile("a")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, []byte("aaa"), data)
+	require.Equal(t, TieredStats{Hot: TierStats{Hits: 1}}, ts.TieredStats())
+
+	t.Run("demotion", func(t *testing.T) {
+		require.NoError(t, ts.PutFile("c", []byte("ccc"), Metadata{})) // "b" is evicted from hot tier
+
+		data, _, ok, _ := cold.GetFile("b")
+		require.True(t, ok)
+		require.Equal(t, []byte("bbb"), data)
+
+		_, _, ok, _ = cold.GetFile("c")
+		require.False(t, ok) // only as many images as needed are demoted
+	})
+
+	t.Run("promotion", func(t *testing.T) {
+		data, _, ok, err := ts.GetFile("b") // from cold tier, "a" is demoted
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, []byte("bbb"), data)
+
+		_, _, ok, _ = ts.GetFile("b") // from hot tier now
+		require.True(t, ok)
+
+		_, _, ok, _ = cold.GetFile("a")
+		require.True(t, ok)
+
+		require.Equal(t, TieredStats{
+			Hot:  TierStats{Hits: 2, Misses: 1},
+			Cold: TierStats{Hits: 1},
+		}, ts.TieredStats())
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		require.NoError(t, ts.PutFile("d", []byte("ddddddd"), Metadata{}))
+
+		_, _, ok, _ := cold.GetFile("d")
+		require.True(t, ok)
+	})
+
+	t.Run("too large overwrite", func(t *testing.T) {
+		require.NoError(t, ts.PutFile("f", []byte("fff"), Metadata{}))
+		require.NoError(t, ts.PutFile("f", []byte("fffffff"), Metadata{}))
+
+		data, _, ok, err := ts.GetFile("f")
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, []byte("fffffff"), data) // the old version is not kept in hot tier
+	})
+
+	t.Run("flush", func(t *testing.T) {
+		require.NoError(t, ts.PutFile("e", []byte("eee"), Metadata{}))
+		require.NoError(t, ts.Flush())
+
+		_, _, ok, _ := cold.GetFile("e")
+		require.True(t, ok)
+	})
+}
+
+func TestTieredPurge(t *testing.T) {
+	cold := NewMemoryStorage(10)
+	ts := NewTieredStorage(10, 6, cold) // hot tier keeps two 3-byte images only
+	purger := ts.(Purger)
+
+	require.NoError(t, purger.PutDerivedFile("http://a.jpg", "/fill/1/1/a.jpg", []byte("p1a"), Metadata{}))
+	require.NoError(t, purger.PutDerivedFile("http://a.jpg", "/fill/2/2/a.jpg", []byte("p2a"), Metadata{}))
+	require.NoError(t, ts.PutFile("http://b.jpg", []byte("bbb"), Metadata{})) // "/fill/1/1/a.jpg" is demoted
+
+	// promoted preview keeps its source, "/fill/2/2/a.jpg" is demoted
+	_, _, ok, _ := ts.GetFile("/fill/1/1/a.jpg")
+	require.True(t, ok)
+
+	purged, err := purger.Purge("http://a.jpg")
+	require.NoError(t, err)
+	require.Equal(t, 3, purged) // promoted preview is counted in both tiers
+
+	for _, path := range []string{"/fill/1/1/a.jpg", "/fill/2/2/a.jpg"} {
+		_, _, ok, _ := ts.GetFile(path)
+		require.False(t, ok, path)
+	}
+
+	// hot tier is not flushed by purge
+	_, _, ok, _ = cold.GetFile("http://b.jpg")
+	require.False(t, ok)
+	_, _, ok, _ = ts.GetFile("http://b.jpg")
+	require.True(t, ok)
+
+	t.Run("prefix", func(t *testing.T) {
+		require.NoError(t, purger.PutDerivedFile("http://c.jpg", "/fill/1/1/c.jpg", []byte("p1c"), Metadata{}))
+
+		purged, err := purger.PurgePrefix("http://c") // source image itself is not stored
+		require.NoError(t, err)
+		require.Equal(t, 1, purged)
+
+		_, _, ok, _ := ts.GetFile("/fill/1/1/c.jpg")
+		require.False(t, ok)
+	})
+}
+
+// countingStorage counts images written into storage.
+type countingStorage struct {
+	Storage
+	puts int
+}
+
+func (cs *countingStorage) PutFile(path string, data []byte, meta Metadata) error {
+	cs.puts++
+
+	return cs.Storage.PutFile(path, data, meta)
+}
+
+func TestTieredPromotedNotDemoted(t *testing.T) {
+	cold := &countingStorage{Storage: NewMemoryStorage(10)}
+	ts := NewTieredStorage(10, 6, cold) // hot tier keeps two 3-byte images only
+
+	for _, path := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, ts.PutFile(path, []byte(path+path+path), Metadata{}))
+	}
+	require.Equal(t, 2, cold.puts) // "a" and "b" are demoted
+
+	// working set is larger than hot tier, promoted images are evicted from it again and again
+	for i := 0; i < 10; i++ {
+		for _, path := range []string{"a", "b", "c", "d"} {
+			_, _, ok, err := ts.GetFile(path)
+			require.NoError(t, err)
+			require.True(t, ok, path)
+		}
+	}
+	require.Equal(t, 4, cold.puts) // "c" and "d" are demoted once, promoted images are not written back
+
+	require.NoError(t, ts.Flush())
+	require.Equal(t, 4, cold.puts)
+}
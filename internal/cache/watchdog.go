@@ -0,0 +1,123 @@
+package cache //nolint:golint,stylecheck
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	DiskOK       = "ok"
+	DiskLow      = "low"      // free space is below low-water mark, cache is evicted
+	DiskCritical = "critical" // free space is below critical threshold, caching is stopped
+)
+
+// guarded is a disk storage which can be watched by Watchdog.
+type guarded interface {
+	dir() string
+	Len() int
+	evict(n int, reason Reason) int
+	pause(paused bool)
+}
+
+type WatchdogConfig struct {
+	LowWater uint64 // free bytes below which cache is evicted
+	Critical uint64 // free bytes below which new files are not written
+}
+
+// WatchdogStatus is the result of the last check of free space.
+type WatchdogStatus struct {
+	Level     string    `json:"level"`
+	Free      uint64    `json:"free"` // bytes after eviction
+	Caching   bool      `json:"caching"`
+	Evicted   uint64    `json:"evicted"`   // keys evicted by watchdog since start
+	Triggered time.Time `json:"triggered"` // time of the last check which found low space, zero if none
+	Checked   time.Time `json:"checked"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// Watchdog keeps free space on volume of disk cache above the limits. It evicts keys chosen by policy
+// while free space is below low-water mark and stops caching while it is below critical threshold.
+type Watchdog struct {
+	disk   guarded
+	config WatchdogConfig
+	free   func(path string) (uint64, error)
+	status WatchdogStatus
+	mutex  *sync.Mutex
+}
+
+// NewWatchdog returns ErrNotSupported if storage (or cold tier of tiered storage) is not on disk.
+func NewWatchdog(storage Storage, config WatchdogConfig) (*Watchdog, error) {
+	if ts, ok := storage.(*tieredStorage); ok {
+		storage = ts.cold
+	}
+	disk, ok := storage.(guarded)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	if config.LowWater < config.Critical {
+		config.LowWater = config.Critical
+	}
+
+	return &Watchdog{
+		disk:   disk,
+		config: config,
+		free:   diskFree,
+		status: WatchdogStatus{Level: DiskOK, Caching: true},
+		mutex:  &sync.Mutex{},
+	}, nil
+}
+
+// Check measures free space and evicts keys in batches of tenth of cache until free space
+// gets above low-water mark or cache gets empty.
+func (w *Watchdog) Check() WatchdogStatus {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	now := time.Now()
+	w.status.Checked = now
+	free, err := w.free(w.disk.dir())
+	if err != nil {
+		w.status.Error = err.Error()
+
+		return w.status
+	}
+	w.status.Error = ""
+
+	level := DiskOK
+	if free < w.config.LowWater {
+		level = DiskLow
+		w.status.Triggered = now
+	}
+	for free < w.config.LowWater {
+		n := w.disk.Len() / 10
+		if n == 0 {
+			n = 1
+		}
+		evicted := w.disk.evict(n, ReasonLowSpace)
+		if evicted == 0 {
+			break
+		}
+		w.status.Evicted += uint64(evicted)
+
+		if free, err = w.free(w.disk.dir()); err != nil {
+			w.status.Error = err.Error()
+
+			break
+		}
+	}
+
+	if free < w.config.Critical {
+		level = DiskCritical
+	}
+	w.disk.pause(level == DiskCritical)
+	w.status.Level, w.status.Free, w.status.Caching = level, free, level != DiskCritical
+
+	return w.status
+}
+
+func (w *Watchdog) Status() WatchdogStatus {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.status
+}
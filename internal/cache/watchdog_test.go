@@ -0,0 +1,80 @@
+package cache //nolint:golint,stylecheck
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchdog(t *testing.T) {
+	for _, shards := range []int{1, 4} {
+		shards := shards
+		t.Run(strconv.Itoa(shards)+" shards", func(t *testing.T) {
+			s, err := NewStorage(StorageConfig{Capacity: 20, Path: "cache_watchdog", Shards: shards})
+			require.NoError(t, err)
+			defer func() {
+				require.NoError(t, s.Clear())
+			}()
+
+			w, err := NewWatchdog(s, WatchdogConfig{LowWater: 500, Critical: 100})
+			require.NoError(t, err)
+			// volume of 1000 bytes is used by cache and other files
+			other := uint64(0)
+			w.free = func(string) (uint64, error) {
+				used := other + uint64(s.(Inspector).Stats().Bytes)
+				if used > 1000 {
+					return 0, nil
+				}
+
+				return 1000 - used, nil
+			}
+
+			for i := 0; i < 10; i++ {
+				require.NoError(t, s.PutFile("/image/"+strconv.Itoa(i)+".jpg", make([]byte, 100), Metadata{}))
+			}
+
+			status := w.Check()
+			require.Equal(t, DiskLow, status.Level)
+			require.True(t, status.Caching)
+			require.GreaterOrEqual(t, status.Free, uint64(500))
+			require.Equal(t, 10-s.(Inspector).Stats().Items, int(status.Evicted))
+			require.False(t, status.Triggered.IsZero())
+
+			require.Equal(t, DiskOK, w.Check().Level)
+
+			other = 950
+			status = w.Check()
+			require.Equal(t, DiskCritical, status.Level)
+			require.False(t, status.Caching)
+			require.Equal(t, 0, s.(Inspector).Stats().Items)
+			err = s.PutFile("/image/new.jpg", []byte("new"), Metadata{})
+			require.True(t, errors.Is(err, ErrCachingPaused))
+
+			other = 0
+			status = w.Check()
+			require.Equal(t, DiskOK, status.Level)
+			require.True(t, status.Caching)
+			require.Equal(t, w.Status(), status)
+			require.NoError(t, s.PutFile("/image/new.jpg", []byte("new"), Metadata{}))
+		})
+	}
+
+	t.Run("not on disk", func(t *testing.T) {
+		_, err := NewWatchdog(NewMemoryStorage(10), WatchdogConfig{LowWater: 500})
+		require.Equal(t, ErrNotSupported, err)
+	})
+
+	t.Run("real volume", func(t *testing.T) {
+		s, err := NewStorage(StorageConfig{Capacity: 10, Path: "cache_watchdog", HotBytes: 100})
+		require.NoError(t, err)
+		defer s.Clear()
+
+		w, err := NewWatchdog(s, WatchdogConfig{LowWater: 1})
+		require.NoError(t, err)
+		status := w.Check()
+		require.Empty(t, status.Error)
+		require.Greater(t, status.Free, uint64(0))
+	})
+}
@@ -0,0 +1,75 @@
+package peers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	replicas = 50
+	timeout  = 30 * time.Second
+
+	// PathPrefix is prepended to preview path in requests between peers.
+	// Peer serves such requests itself and never forwards them further.
+	PathPrefix = "/peer"
+)
+
+var ErrPeerFailed = errors.New("peer request failed")
+
+// Pool knows all instances of the service and which one owns a preview.
+type Pool struct {
+	self   string
+	ring   *Ring
+	client *http.Client
+}
+
+// NewPool creates pool of peers given by base URLs like http://10.0.0.1:8080, self must be one of them.
+func NewPool(self string, peers []string) *Pool {
+	normalized := make([]string, 0, len(peers))
+	for _, peer := range peers {
+		normalized = append(normalized, strings.TrimSuffix(peer, "/"))
+	}
+
+	return &Pool{
+		self:   strings.TrimSuffix(self, "/"),
+		ring:   NewRing(replicas, normalized...),
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Owner returns base URL of peer owning key and reports whether it is another instance.
+func (p *Pool) Owner(key string) (string, bool) {
+	owner := p.ring.Get(key)
+
+	return owner, owner != "" && owner != p.self
+}
+
+// Fetch requests preview from owner peer.
+func (p *Pool) Fetch(owner string, path string, header http.Header) ([]byte, http.Header, error) {
+	rq, err := http.NewRequestWithContext(context.Background(), http.MethodGet, owner+PathPrefix+path, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", ErrPeerFailed, err)
+	}
+	rq.Header = header.Clone()
+
+	rs, err := p.client.Do(rq)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", ErrPeerFailed, err)
+	}
+	defer rs.Body.Close()
+
+	data, err := ioutil.ReadAll(rs.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", ErrPeerFailed, err)
+	}
+	if rs.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("%s: %s responded with status %d", ErrPeerFailed, owner, rs.StatusCode)
+	}
+
+	return data, rs.Header.Clone(), nil
+}
@@ -0,0 +1,53 @@
+package peers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPool(t *testing.T) {
+	peer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != PathPrefix+"/fill/50/50/example.com/image.jpg" {
+			w.WriteHeader(http.StatusBadGateway)
+
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		_, _ = w.Write([]byte(r.Header.Get("Header-One")))
+	}))
+	defer peer.Close()
+
+	t.Run("owner", func(t *testing.T) {
+		pool := NewPool("http://self", []string{"http://self/", peer.URL})
+		for _, key := range []string{"a", "b", "c", "d", "e"} {
+			owner, remote := pool.Owner(key)
+			require.Contains(t, []string{"http://self", peer.URL}, owner)
+			require.Equal(t, owner == peer.URL, remote)
+		}
+
+		owner, remote := NewPool("http://self", []string{"http://self"}).Owner("a")
+		require.Equal(t, "http://self", owner)
+		require.False(t, remote)
+	})
+
+	t.Run("fetch", func(t *testing.T) {
+		pool := NewPool("http://self", []string{"http://self", peer.URL})
+		header := http.Header{}
+		header.Set("Header-One", "test-header-one")
+
+		data, rsHeader, err := pool.Fetch(peer.URL, "/fill/50/50/example.com/image.jpg", header)
+		require.NoError(t, err)
+		require.Equal(t, "test-header-one", string(data))
+		require.Equal(t, "image/jpeg", rsHeader.Get("Content-Type"))
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		pool := NewPool("http://self", []string{"http://self", peer.URL})
+
+		_, _, err := pool.Fetch(peer.URL, "/fill/60/60/example.com/image.jpg", http.Header{})
+		require.EqualError(t, err, "peer request failed: "+peer.URL+" responded with status 502")
+	})
+}
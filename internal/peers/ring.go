@@ -0,0 +1,48 @@
+package peers
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+)
+
+// Ring maps keys to nodes with consistent hashing, so adding or removing a node
+// moves only keys of that node. Every node is placed on the ring many times to spread keys evenly.
+type Ring struct {
+	replicas int
+	hashes   []uint32 // sorted
+	nodes    map[uint32]string
+}
+
+func NewRing(replicas int, nodes ...string) *Ring {
+	r := &Ring{
+		replicas: replicas,
+		nodes:    make(map[uint32]string),
+	}
+
+	for _, node := range nodes {
+		for i := 0; i < replicas; i++ {
+			hash := crc32.ChecksumIEEE([]byte(strconv.Itoa(i) + node))
+			r.hashes = append(r.hashes, hash)
+			r.nodes[hash] = node
+		}
+	}
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+
+	return r
+}
+
+// Get returns node owning key, empty string if ring is empty.
+func (r *Ring) Get(key string) string {
+	if len(r.hashes) == 0 {
+		return ""
+	}
+
+	hash := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= hash })
+	if i == len(r.hashes) {
+		i = 0
+	}
+
+	return r.nodes[r.hashes[i]]
+}
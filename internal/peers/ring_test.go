@@ -0,0 +1,46 @@
+package peers
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRing(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		require.Equal(t, "", NewRing(replicas).Get("key"))
+	})
+
+	t.Run("stable", func(t *testing.T) {
+		a := NewRing(replicas, "a", "b", "c")
+		b := NewRing(replicas, "c", "a", "b")
+		for i := 0; i < 100; i++ {
+			key := "/fill/50/50/example.com/" + strconv.Itoa(i) + ".jpg"
+			require.Equal(t, a.Get(key), b.Get(key))
+		}
+	})
+
+	t.Run("balanced", func(t *testing.T) {
+		r := NewRing(replicas, "a", "b", "c")
+		counts := map[string]int{}
+		for i := 0; i < 3000; i++ {
+			counts[r.Get("/fill/50/50/example.com/"+strconv.Itoa(i)+".jpg")]++
+		}
+		require.Len(t, counts, 3)
+		for _, count := range counts {
+			require.Greater(t, count, 500)
+		}
+	})
+
+	t.Run("node added", func(t *testing.T) {
+		before := NewRing(replicas, "a", "b", "c")
+		after := NewRing(replicas, "a", "b", "c", "d")
+		for i := 0; i < 1000; i++ {
+			key := "/fill/50/50/example.com/" + strconv.Itoa(i) + ".jpg"
+			if owner := after.Get(key); owner != "d" {
+				require.Equal(t, before.Get(key), owner) // only keys of the new node move
+			}
+		}
+	})
+}
@@ -1,10 +1,13 @@
 package settings
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/textproto"
 	"os"
 	"strconv"
+	"strings"
 )
 
 const ( // foolproof
@@ -20,6 +23,15 @@ const ( // foolproof
 	maxMaxWidth  = 10000
 	minMaxHeight = maxMinHeight + 1
 	maxMaxHeight = 10000
+	minHotBytes  = 0
+	maxHotBytes  = 1 << 34
+	minShards    = 1
+	maxShards    = 256
+	minWorkers   = 1
+	maxWorkers   = 64
+	minDiskMB    = 0
+	maxDiskMB    = 1 << 20
+	minKeyLength = 16
 )
 
 type Settings struct {
@@ -29,8 +41,63 @@ type Settings struct {
 	minHeight int // ~IMAGE_PREVIEWER_MIN_HEIGHT
 	maxWidth  int // ~IMAGE_PREVIEWER_MAX_WIDTH
 	maxHeight int // ~IMAGE_PREVIEWER_MAX_HEIGHT
+
+	cachePersistent bool     // ~IMAGE_PREVIEWER_CACHE_PERSISTENT, optional
+	cacheBackend    string   // ~IMAGE_PREVIEWER_CACHE_BACKEND, optional
+	redisAddr       string   // ~IMAGE_PREVIEWER_REDIS_ADDR, optional
+	cacheHotBytes   int64    // ~IMAGE_PREVIEWER_CACHE_HOT_BYTES, optional
+	cachePolicy     string   // ~IMAGE_PREVIEWER_CACHE_POLICY, optional
+	cacheShards     int      // ~IMAGE_PREVIEWER_CACHE_SHARDS, optional
+	adminToken      string   // ~IMAGE_PREVIEWER_ADMIN_TOKEN, optional
+	warmUpFile      string   // ~IMAGE_PREVIEWER_WARMUP_FILE, optional
+	warmUpWorkers   int      // ~IMAGE_PREVIEWER_WARMUP_CONCURRENCY, optional
+	peers           []string // ~IMAGE_PREVIEWER_PEERS, optional
+	peerSelf        string   // ~IMAGE_PREVIEWER_PEER_SELF, required if peers are set
+	diskLowWaterMB  int      // ~IMAGE_PREVIEWER_DISK_LOW_WATER_MB, optional
+	diskCriticalMB  int      // ~IMAGE_PREVIEWER_DISK_CRITICAL_MB, optional
+	cacheControl    string   // ~IMAGE_PREVIEWER_CACHE_CONTROL, optional
+	passHeaders     []string // ~IMAGE_PREVIEWER_PASS_HEADERS, optional
+	signingKeys     []string // ~IMAGE_PREVIEWER_SIGNING_KEYS, optional
+	presetsOnly     bool     // ~IMAGE_PREVIEWER_PRESETS_ONLY, optional
+
+	forwardHeaders []string                     // ~IMAGE_PREVIEWER_FORWARD_HEADERS, optional
+	originHeaders  map[string]map[string]string // ~IMAGE_PREVIEWER_ORIGIN_HEADERS, optional
+	presets        map[string]Preset            // ~IMAGE_PREVIEWER_PRESETS, optional
+}
+
+// Preset is a named preview, zero quality is a default quality of encoder and empty crop is center.
+type Preset struct {
+	Mode    string `json:"mode"`
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+	Quality int    `json:"quality"`
+	Crop    string `json:"crop"`
 }
 
+const (
+	defaultCacheBackend = "disk"
+	defaultRedisAddr    = "localhost:6379"
+	defaultCachePolicy  = "lru"
+	defaultWorkers      = 4
+	defaultCacheControl = "public, max-age=86400"
+)
+
+var (
+	defaultForwardHeaders = []string{"Accept", "Accept-Language", "User-Agent"}
+
+	cacheBackends = []string{"disk", "memory", "redis"}
+	cachePolicies = []string{"lru", "lfu", "2q", "arc"}
+	presetCrops   = []string{"", "center", "smart"}
+	// headers of origin response which describe its body or connection, or leak origin state
+	unsafeHeaders = []string{"Connection", "Content-Encoding", "Content-Length", "Content-Range", "Keep-Alive",
+		"Proxy-Authenticate", "Set-Cookie", "Trailer", "Transfer-Encoding", "Upgrade"}
+	// headers of client request which are hop-by-hop (RFC 7230, section 6.1), describe client copy of preview
+	// or are set by previewer itself
+	unforwardableHeaders = []string{"Connection", "Content-Length", "Host", "If-Match", "If-Modified-Since",
+		"If-None-Match", "If-Range", "If-Unmodified-Since", "Keep-Alive", "Proxy-Authenticate",
+		"Proxy-Authorization", "Te", "Trailer", "Transfer-Encoding", "Upgrade", "Via", "X-Forwarded-For"}
+)
+
 var ErrCanNotGetSettings = errors.New("can not get settings")
 
 func (s *Settings) ParseEnv() error {
@@ -82,6 +149,165 @@ func (s *Settings) ParseEnv() error {
 	}
 	s.maxHeight = maxHeight
 
+	cachePersistent, err := parseBoolVar("IMAGE_PREVIEWER_CACHE_PERSISTENT", false)
+	if err != nil {
+		s.Reset()
+
+		return fmt.Errorf("%s: %w", ErrCanNotGetSettings, err)
+	}
+	s.cachePersistent = cachePersistent
+
+	cacheBackend, err := parseStringVar("IMAGE_PREVIEWER_CACHE_BACKEND", defaultCacheBackend, cacheBackends)
+	if err != nil {
+		s.Reset()
+
+		return fmt.Errorf("%s: %w", ErrCanNotGetSettings, err)
+	}
+	s.cacheBackend = cacheBackend
+
+	redisAddr, err := parseStringVar("IMAGE_PREVIEWER_REDIS_ADDR", defaultRedisAddr, nil)
+	if err != nil {
+		s.Reset()
+
+		return fmt.Errorf("%s: %w", ErrCanNotGetSettings, err)
+	}
+	s.redisAddr = redisAddr
+
+	cacheHotBytes, err := parseOptionalInt64Var("IMAGE_PREVIEWER_CACHE_HOT_BYTES", 0, minHotBytes, maxHotBytes)
+	if err != nil {
+		s.Reset()
+
+		return fmt.Errorf("%s: %w", ErrCanNotGetSettings, err)
+	}
+	s.cacheHotBytes = cacheHotBytes
+
+	cachePolicy, err := parseStringVar("IMAGE_PREVIEWER_CACHE_POLICY", defaultCachePolicy, cachePolicies)
+	if err != nil {
+		s.Reset()
+
+		return fmt.Errorf("%s: %w", ErrCanNotGetSettings, err)
+	}
+	s.cachePolicy = cachePolicy
+
+	cacheShards, err := parseOptionalIntVar("IMAGE_PREVIEWER_CACHE_SHARDS", 1, minShards, maxShards)
+	if err != nil {
+		s.Reset()
+
+		return fmt.Errorf("%s: %w", ErrCanNotGetSettings, err)
+	}
+	s.cacheShards = cacheShards
+
+	adminToken, err := parseStringVar("IMAGE_PREVIEWER_ADMIN_TOKEN", "", nil)
+	if err != nil {
+		s.Reset()
+
+		return fmt.Errorf("%s: %w", ErrCanNotGetSettings, err)
+	}
+	s.adminToken = adminToken
+
+	warmUpFile, err := parseStringVar("IMAGE_PREVIEWER_WARMUP_FILE", "", nil)
+	if err != nil {
+		s.Reset()
+
+		return fmt.Errorf("%s: %w", ErrCanNotGetSettings, err)
+	}
+	s.warmUpFile = warmUpFile
+
+	warmUpWorkers, err := parseOptionalIntVar("IMAGE_PREVIEWER_WARMUP_CONCURRENCY", defaultWorkers, minWorkers, maxWorkers)
+	if err != nil {
+		s.Reset()
+
+		return fmt.Errorf("%s: %w", ErrCanNotGetSettings, err)
+	}
+	s.warmUpWorkers = warmUpWorkers
+
+	peers, peerSelf, err := parsePeers()
+	if err != nil {
+		s.Reset()
+
+		return fmt.Errorf("%s: %w", ErrCanNotGetSettings, err)
+	}
+	s.peers, s.peerSelf = peers, peerSelf
+
+	diskLowWaterMB, err := parseOptionalIntVar("IMAGE_PREVIEWER_DISK_LOW_WATER_MB", 0, minDiskMB, maxDiskMB)
+	if err != nil {
+		s.Reset()
+
+		return fmt.Errorf("%s: %w", ErrCanNotGetSettings, err)
+	}
+	s.diskLowWaterMB = diskLowWaterMB
+
+	diskCriticalMB, err := parseOptionalIntVar("IMAGE_PREVIEWER_DISK_CRITICAL_MB", 0, minDiskMB, maxDiskMB)
+	if err == nil && diskLowWaterMB > 0 && diskCriticalMB > diskLowWaterMB {
+		err = errors.New("IMAGE_PREVIEWER_DISK_CRITICAL_MB value must not exceed IMAGE_PREVIEWER_DISK_LOW_WATER_MB")
+	}
+	if err != nil {
+		s.Reset()
+
+		return fmt.Errorf("%s: %w", ErrCanNotGetSettings, err)
+	}
+	s.diskCriticalMB = diskCriticalMB
+
+	cacheControl, err := parseStringVar("IMAGE_PREVIEWER_CACHE_CONTROL", defaultCacheControl, nil)
+	if err != nil {
+		s.Reset()
+
+		return fmt.Errorf("%s: %w", ErrCanNotGetSettings, err)
+	}
+	s.cacheControl = cacheControl
+
+	passHeaders, err := parseHeaderList("IMAGE_PREVIEWER_PASS_HEADERS", nil, unsafeHeaders)
+	if err != nil {
+		s.Reset()
+
+		return fmt.Errorf("%s: %w", ErrCanNotGetSettings, err)
+	}
+	s.passHeaders = passHeaders
+
+	forwardHeaders, err := parseHeaderList("IMAGE_PREVIEWER_FORWARD_HEADERS", defaultForwardHeaders,
+		unforwardableHeaders)
+	if err != nil {
+		s.Reset()
+
+		return fmt.Errorf("%s: %w", ErrCanNotGetSettings, err)
+	}
+	s.forwardHeaders = forwardHeaders
+
+	originHeaders, err := parseOriginHeaders()
+	if err != nil {
+		s.Reset()
+
+		return fmt.Errorf("%s: %w", ErrCanNotGetSettings, err)
+	}
+	s.originHeaders = originHeaders
+
+	signingKeys, err := parseSigningKeys()
+	if err != nil {
+		s.Reset()
+
+		return fmt.Errorf("%s: %w", ErrCanNotGetSettings, err)
+	}
+	s.signingKeys = signingKeys
+
+	presets, err := parsePresets(minWidth, minHeight, maxWidth, maxHeight)
+	if err != nil {
+		s.Reset()
+
+		return fmt.Errorf("%s: %w", ErrCanNotGetSettings, err)
+	}
+	s.presets = presets
+
+	presetsOnly, err := parseBoolVar("IMAGE_PREVIEWER_PRESETS_ONLY", false)
+	if err == nil && presetsOnly && len(presets) == 0 {
+		err = errors.New("IMAGE_PREVIEWER_PRESETS_ONLY requires IMAGE_PREVIEWER_PRESETS")
+	}
+	if err != nil {
+		s.Reset()
+
+		return fmt.Errorf("%s: %w", ErrCanNotGetSettings, err)
+	}
+	s.presetsOnly = presetsOnly
+
 	return nil
 }
 
@@ -109,8 +335,117 @@ func (s *Settings) GetMaxHeight() int {
 	return s.maxHeight
 }
 
+// GetCachePersistent reports whether cache files must be kept between restarts.
+func (s *Settings) GetCachePersistent() bool {
+	return s.cachePersistent
+}
+
+// GetCacheBackend returns one of "disk", "memory" or "redis".
+func (s *Settings) GetCacheBackend() string {
+	return s.cacheBackend
+}
+
+func (s *Settings) GetRedisAddr() string {
+	return s.redisAddr
+}
+
+// GetCacheHotBytes returns budget of in-memory cache tier, 0 means the tier is disabled.
+func (s *Settings) GetCacheHotBytes() int64 {
+	return s.cacheHotBytes
+}
+
+// GetCachePolicy returns one of "lru", "lfu", "2q" or "arc".
+func (s *Settings) GetCachePolicy() string {
+	return s.cachePolicy
+}
+
+// GetCacheShards returns count of independent partitions of disk cache index.
+func (s *Settings) GetCacheShards() int {
+	return s.cacheShards
+}
+
+// GetAdminToken returns bearer token of admin endpoints, they are disabled if it is empty.
+func (s *Settings) GetAdminToken() string {
+	return s.adminToken
+}
+
+// GetWarmUpFile returns file with preview paths to render at startup, empty if warm-up is disabled.
+func (s *Settings) GetWarmUpFile() string {
+	return s.warmUpFile
+}
+
+// GetWarmUpConcurrency returns max count of previews rendered at once during warm-up.
+func (s *Settings) GetWarmUpConcurrency() int {
+	return s.warmUpWorkers
+}
+
+// GetPeers returns base URLs of all instances sharing cache, including this one; empty if peering is disabled.
+func (s *Settings) GetPeers() []string {
+	return s.peers
+}
+
+// GetPeerSelf returns base URL of this instance as it is listed in peers.
+func (s *Settings) GetPeerSelf() string {
+	return s.peerSelf
+}
+
+// GetDiskLowWaterMB returns free space on cache volume below which cache is evicted, 0 if it is not watched.
+func (s *Settings) GetDiskLowWaterMB() int {
+	return s.diskLowWaterMB
+}
+
+// GetDiskCriticalMB returns free space on cache volume below which caching is stopped, 0 if it is not watched.
+func (s *Settings) GetDiskCriticalMB() int {
+	return s.diskCriticalMB
+}
+
+// GetCacheControl returns Cache-Control header of preview responses.
+func (s *Settings) GetCacheControl() string {
+	return s.cacheControl
+}
+
+// GetPassHeaders returns canonical names of origin response headers passed to client.
+func (s *Settings) GetPassHeaders() []string {
+	return s.passHeaders
+}
+
+// GetForwardHeaders returns canonical names of client request headers forwarded to origin.
+func (s *Settings) GetForwardHeaders() []string {
+	return s.forwardHeaders
+}
+
+// GetOriginHeaders returns headers added to requests by origin host, i.e. "cdn.example.com" or "cdn:8080".
+func (s *Settings) GetOriginHeaders() map[string]map[string]string {
+	return s.originHeaders
+}
+
+// GetSigningKeys returns keys of preview URL signatures, the first one signs URLs and all of them verify.
+// URLs are not signed if it is empty.
+func (s *Settings) GetSigningKeys() []string {
+	return s.signingKeys
+}
+
+// GetPresets returns named presets served by /preset/{name}/{URL}.
+func (s *Settings) GetPresets() map[string]Preset {
+	return s.presets
+}
+
+// GetPresetsOnly returns true if previews of raw dimensions are rejected.
+func (s *Settings) GetPresetsOnly() bool {
+	return s.presetsOnly
+}
+
 func (s *Settings) Reset() {
 	s.port, s.cacheSize, s.minWidth, s.minHeight, s.maxWidth, s.maxHeight = 0, 0, 0, 0, 0, 0
+	s.cachePersistent, s.cacheBackend, s.redisAddr, s.cacheHotBytes = false, "", "", 0
+	s.cachePolicy, s.cacheShards, s.adminToken = "", 0, ""
+	s.warmUpFile, s.warmUpWorkers = "", 0
+	s.peers, s.peerSelf = nil, ""
+	s.diskLowWaterMB, s.diskCriticalMB = 0, 0
+	s.cacheControl, s.passHeaders = "", nil
+	s.forwardHeaders, s.originHeaders = nil, nil
+	s.signingKeys = nil
+	s.presets, s.presetsOnly = nil, false
 }
 
 func parseIntVar(name string, min int, max int) (int, error) {
@@ -125,3 +460,229 @@ func parseIntVar(name string, min int, max int) (int, error) {
 
 	return value, nil
 }
+
+// parseOptionalIntVar returns def if variable is not set.
+func parseOptionalIntVar(name string, def int, min int, max int) (int, error) {
+	if value, ok := os.LookupEnv(name); !ok || value == "" {
+		return def, nil
+	}
+
+	return parseIntVar(name, min, max)
+}
+
+// parseOptionalInt64Var returns def if variable is not set, it is used for sizes in bytes which may exceed int
+// on 32-bit platforms.
+func parseOptionalInt64Var(name string, def int64, min int64, max int64) (int64, error) {
+	source, ok := os.LookupEnv(name)
+	if !ok || source == "" {
+		return def, nil
+	}
+
+	value, err := strconv.ParseInt(source, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("can not parse %s", name)
+	}
+
+	if value < min || value > max {
+		return 0, fmt.Errorf("%s value must be in range [%d, %d]", name, min, max)
+	}
+
+	return value, nil
+}
+
+// parseBoolVar returns def if variable is not set.
+func parseBoolVar(name string, def bool) (bool, error) {
+	source, ok := os.LookupEnv(name)
+	if !ok || source == "" {
+		return def, nil
+	}
+
+	value, err := strconv.ParseBool(source)
+	if err != nil {
+		return false, fmt.Errorf("can not parse %s", name)
+	}
+
+	return value, nil
+}
+
+// parseStringVar returns def if variable is not set, allowed values are not checked if empty.
+func parseStringVar(name string, def string, allowed []string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return def, nil
+	}
+
+	if len(allowed) == 0 {
+		return value, nil
+	}
+	for _, a := range allowed {
+		if value == a {
+			return value, nil
+		}
+	}
+
+	return "", fmt.Errorf("%s value must be one of %v", name, allowed)
+}
+
+// parsePeers returns comma-separated peers list, this instance must be one of peers.
+func parsePeers() ([]string, string, error) {
+	list, _ := parseStringVar("IMAGE_PREVIEWER_PEERS", "", nil)
+	self, _ := parseStringVar("IMAGE_PREVIEWER_PEER_SELF", "", nil)
+	if list == "" {
+		return nil, "", nil
+	}
+
+	peers := []string{}
+	found := false
+	for _, peer := range strings.Split(list, ",") {
+		peer = strings.TrimSuffix(strings.TrimSpace(peer), "/")
+		if peer == "" {
+			continue
+		}
+		if !strings.HasPrefix(peer, "http://") && !strings.HasPrefix(peer, "https://") {
+			return nil, "", fmt.Errorf("IMAGE_PREVIEWER_PEERS value %s must be a http(s) URL", peer)
+		}
+		if peer == strings.TrimSuffix(self, "/") {
+			found = true
+		}
+		peers = append(peers, peer)
+	}
+
+	if !found {
+		return nil, "", errors.New("IMAGE_PREVIEWER_PEER_SELF value must be one of IMAGE_PREVIEWER_PEERS")
+	}
+
+	return peers, strings.TrimSuffix(self, "/"), nil
+}
+
+// parseHeaderList returns canonical names of comma-separated headers or def if variable is not set,
+// forbidden headers are not allowed.
+func parseHeaderList(name string, def []string, forbidden []string) ([]string, error) {
+	list, _ := parseStringVar(name, "", nil)
+	if list == "" {
+		return def, nil
+	}
+
+	headers := []string{}
+	for _, header := range strings.Split(list, ",") {
+		header = textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(header))
+		if header == "" {
+			continue
+		}
+		if err := checkHeader(name, header, forbidden); err != nil {
+			return nil, err
+		}
+		headers = append(headers, header)
+	}
+
+	return headers, nil
+}
+
+func checkHeader(name string, header string, forbidden []string) error {
+	for _, f := range forbidden {
+		if header == f {
+			return fmt.Errorf("%s value must not contain %s", name, header)
+		}
+	}
+
+	return nil
+}
+
+// parseOriginHeaders returns headers by origin host from JSON like {"cdn.example.com": {"Authorization": "..."}}.
+// Header names are canonicalized, hosts are lowercased.
+func parseOriginHeaders() (map[string]map[string]string, error) {
+	const name = "IMAGE_PREVIEWER_ORIGIN_HEADERS"
+	source, _ := parseStringVar(name, "", nil)
+	if source == "" {
+		return nil, nil
+	}
+
+	parsed := map[string]map[string]string{}
+	if err := json.Unmarshal([]byte(source), &parsed); err != nil {
+		return nil, fmt.Errorf("can not parse %s", name)
+	}
+
+	origins := make(map[string]map[string]string, len(parsed))
+	for host, headers := range parsed {
+		canonical := make(map[string]string, len(headers))
+		for header, value := range headers {
+			header = textproto.CanonicalMIMEHeaderKey(header)
+			if err := checkHeader(name, header, unforwardableHeaders); err != nil {
+				return nil, err
+			}
+			canonical[header] = value
+		}
+		origins[strings.ToLower(host)] = canonical
+	}
+
+	return origins, nil
+}
+
+// parseSigningKeys returns comma-separated keys, the new key goes first while the old one is still accepted.
+func parseSigningKeys() ([]string, error) {
+	const name = "IMAGE_PREVIEWER_SIGNING_KEYS"
+	list, _ := parseStringVar(name, "", nil)
+	if list == "" {
+		return nil, nil
+	}
+
+	keys := []string{}
+	for _, key := range strings.Split(list, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		if len(key) < minKeyLength {
+			return nil, fmt.Errorf("%s keys must be at least %d characters long", name, minKeyLength)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// parsePresets returns presets from JSON like {"thumb": {"width": 150, "height": 150, "quality": 80, "crop": "smart"}}.
+// Preset dimensions must be in the same ranges as requested ones.
+func parsePresets(minWidth int, minHeight int, maxWidth int, maxHeight int) (map[string]Preset, error) {
+	const name = "IMAGE_PREVIEWER_PRESETS"
+	source, _ := parseStringVar(name, "", nil)
+	if source == "" {
+		return nil, nil
+	}
+
+	presets := map[string]Preset{}
+	if err := json.Unmarshal([]byte(source), &presets); err != nil {
+		return nil, fmt.Errorf("can not parse %s", name)
+	}
+
+	for key, preset := range presets {
+		switch {
+		case key == "" || strings.ContainsAny(key, "/?#@"):
+			return nil, fmt.Errorf("%s preset name %q must be a path segment", name, key)
+		case preset.Mode != "" && preset.Mode != "fill":
+			return nil, fmt.Errorf("%s preset %s mode must be fill", name, key)
+		case preset.Width < minWidth || preset.Width > maxWidth:
+			return nil, fmt.Errorf("%s preset %s width value must be in range [%d, %d]", name, key, minWidth, maxWidth)
+		case preset.Height < minHeight || preset.Height > maxHeight:
+			return nil, fmt.Errorf("%s preset %s height value must be in range [%d, %d]", name, key, minHeight,
+				maxHeight)
+		case preset.Quality < 0 || preset.Quality > 100:
+			return nil, fmt.Errorf("%s preset %s quality value must be in range [0, 100], 0 = default", name, key)
+		}
+		if err := checkCrop(name, key, preset.Crop); err != nil {
+			return nil, err
+		}
+	}
+
+	return presets, nil
+}
+
+func checkCrop(name string, key string, crop string) error {
+	for _, c := range presetCrops {
+		if crop == c {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s preset %s crop must be one of %v", name, key, presetCrops[1:])
+}
@@ -29,134 +29,138 @@ type (
 
 var testCases = []testCase{
 	{
-		name:     "positive",
-		env:      environment{"8080", "5", "50", "50", "2000", "2000"},
-		expected: &Settings{8080, 5, 50, 50, 2000, 2000},
-		err:      nil,
+		name: "positive",
+		env:  environment{"8080", "5", "50", "50", "2000", "2000"},
+		expected: &Settings{
+			port: 8080, cacheSize: 5, minWidth: 50, minHeight: 50, maxWidth: 2000, maxHeight: 2000,
+			cacheBackend: "disk", redisAddr: "localhost:6379", cachePolicy: "lru", cacheShards: 1,
+			warmUpWorkers: 4, cacheControl: "public, max-age=86400", forwardHeaders: defaultForwardHeaders,
+		},
+		err: nil,
 	},
 	{
 		name:     "canNotParsePort",
 		env:      environment{"port", "5", "50", "50", "2000", "2000"},
-		expected: &Settings{0, 0, 0, 0, 0, 0},
+		expected: &Settings{},
 		err: fmt.Errorf("%s: %w", ErrCanNotGetSettings,
 			errors.New("can not parse IMAGE_PREVIEWER_PORT")),
 	},
 	{
 		name:     "canNotParseCacheSize",
 		env:      environment{"8080", "cacheSize", "50", "50", "2000", "2000"},
-		expected: &Settings{0, 0, 0, 0, 0, 0},
+		expected: &Settings{},
 		err: fmt.Errorf("%s: %w", ErrCanNotGetSettings,
 			errors.New("can not parse IMAGE_PREVIEWER_CACHE_SIZE")),
 	},
 	{
 		name:     "canNotParseMinWidth",
 		env:      environment{"8080", "5", "minWidth", "50", "2000", "2000"},
-		expected: &Settings{0, 0, 0, 0, 0, 0},
+		expected: &Settings{},
 		err: fmt.Errorf("%s: %w", ErrCanNotGetSettings,
 			errors.New("can not parse IMAGE_PREVIEWER_MIN_WIDTH")),
 	},
 	{
 		name:     "canNotParseMinHeight",
 		env:      environment{"8080", "5", "50", "minHeight", "2000", "2000"},
-		expected: &Settings{0, 0, 0, 0, 0, 0},
+		expected: &Settings{},
 		err: fmt.Errorf("%s: %w", ErrCanNotGetSettings,
 			errors.New("can not parse IMAGE_PREVIEWER_MIN_HEIGHT")),
 	},
 	{
 		name:     "canNotParseMaxWidth",
 		env:      environment{"8080", "5", "50", "50", "maxWidth", "2000"},
-		expected: &Settings{0, 0, 0, 0, 0, 0},
+		expected: &Settings{},
 		err: fmt.Errorf("%s: %w", ErrCanNotGetSettings,
 			errors.New("can not parse IMAGE_PREVIEWER_MAX_WIDTH")),
 	},
 	{
 		name:     "canNotParseMaxHeight",
 		env:      environment{"8080", "5", "50", "50", "2000", "maxHeight"},
-		expected: &Settings{0, 0, 0, 0, 0, 0},
+		expected: &Settings{},
 		err: fmt.Errorf("%s: %w", ErrCanNotGetSettings,
 			errors.New("can not parse IMAGE_PREVIEWER_MAX_HEIGHT")),
 	},
 	{
 		name:     "portBoundsLeft",
 		env:      environment{"-1", "5", "50", "50", "2000", "2000"},
-		expected: &Settings{0, 0, 0, 0, 0, 0},
+		expected: &Settings{},
 		err: fmt.Errorf("%s: %w", ErrCanNotGetSettings,
 			fmt.Errorf("IMAGE_PREVIEWER_PORT value must be in range [%d, %d]", minPort, maxPort)),
 	},
 	{
 		name:     "portBoundsRight",
 		env:      environment{"65536", "5", "50", "50", "2000", "2000"},
-		expected: &Settings{0, 0, 0, 0, 0, 0},
+		expected: &Settings{},
 		err: fmt.Errorf("%s: %w", ErrCanNotGetSettings,
 			fmt.Errorf("IMAGE_PREVIEWER_PORT value must be in range [%d, %d]", minPort, maxPort)),
 	},
 	{
 		name:     "cacheSizeBoundsLeft",
 		env:      environment{"8080", "0", "50", "50", "2000", "2000"},
-		expected: &Settings{0, 0, 0, 0, 0, 0},
+		expected: &Settings{},
 		err: fmt.Errorf("%s: %w", ErrCanNotGetSettings,
 			fmt.Errorf("IMAGE_PREVIEWER_CACHE_SIZE value must be in range [%d, %d]", minCacheSize, maxCacheSize)),
 	},
 	{
 		name:     "cacheSizeBoundsRight",
 		env:      environment{"8080", "10001", "50", "50", "2000", "2000"},
-		expected: &Settings{0, 0, 0, 0, 0, 0},
+		expected: &Settings{},
 		err: fmt.Errorf("%s: %w", ErrCanNotGetSettings,
 			fmt.Errorf("IMAGE_PREVIEWER_CACHE_SIZE value must be in range [%d, %d]", minCacheSize, maxCacheSize)),
 	},
 	{
 		name:     "minWidthBoundsLeft",
 		env:      environment{"8080", "5", "0", "50", "2000", "2000"},
-		expected: &Settings{0, 0, 0, 0, 0, 0},
+		expected: &Settings{},
 		err: fmt.Errorf("%s: %w", ErrCanNotGetSettings,
 			fmt.Errorf("IMAGE_PREVIEWER_MIN_WIDTH value must be in range [%d, %d]", minMinWidth, maxMinWidth)),
 	},
 	{
 		name:     "minWidthBoundsRight",
 		env:      environment{"8080", "5", "1001", "50", "2000", "2000"},
-		expected: &Settings{0, 0, 0, 0, 0, 0},
+		expected: &Settings{},
 		err: fmt.Errorf("%s: %w", ErrCanNotGetSettings,
 			fmt.Errorf("IMAGE_PREVIEWER_MIN_WIDTH value must be in range [%d, %d]", minMinWidth, maxMinWidth)),
 	},
 	{
 		name:     "minHeightBoundsLeft",
 		env:      environment{"8080", "5", "50", "0", "2000", "2000"},
-		expected: &Settings{0, 0, 0, 0, 0, 0},
+		expected: &Settings{},
 		err: fmt.Errorf("%s: %w", ErrCanNotGetSettings,
 			fmt.Errorf("IMAGE_PREVIEWER_MIN_HEIGHT value must be in range [%d, %d]", minMinHeight, maxMinHeight)),
 	},
 	{
 		name:     "minHeightBoundsRight",
 		env:      environment{"8080", "5", "50", "1001", "2000", "2000"},
-		expected: &Settings{0, 0, 0, 0, 0, 0},
+		expected: &Settings{},
 		err: fmt.Errorf("%s: %w", ErrCanNotGetSettings,
 			fmt.Errorf("IMAGE_PREVIEWER_MIN_HEIGHT value must be in range [%d, %d]", minMinHeight, maxMinHeight)),
 	},
 	{
 		name:     "maxWidthBoundsLeft",
 		env:      environment{"8080", "5", "50", "50", "1000", "2000"},
-		expected: &Settings{0, 0, 0, 0, 0, 0},
+		expected: &Settings{},
 		err: fmt.Errorf("%s: %w", ErrCanNotGetSettings,
 			fmt.Errorf("IMAGE_PREVIEWER_MAX_WIDTH value must be in range [%d, %d]", minMaxWidth, maxMaxWidth)),
 	},
 	{
 		name:     "maxWidthBoundsRight",
 		env:      environment{"8080", "5", "50", "50", "10001", "2000"},
-		expected: &Settings{0, 0, 0, 0, 0, 0},
+		expected: &Settings{},
 		err: fmt.Errorf("%s: %w", ErrCanNotGetSettings,
 			fmt.Errorf("IMAGE_PREVIEWER_MAX_WIDTH value must be in range [%d, %d]", minMaxWidth, maxMaxWidth)),
 	},
 	{
 		name:     "maxHeightBoundsLeft",
 		env:      environment{"8080", "5", "50", "50", "2000", "1000"},
-		expected: &Settings{0, 0, 0, 0, 0, 0},
+		expected: &Settings{},
 		err: fmt.Errorf("%s: %w", ErrCanNotGetSettings,
 			fmt.Errorf("IMAGE_PREVIEWER_MAX_HEIGHT value must be in range [%d, %d]", minMaxHeight, maxMaxHeight)),
 	},
 	{
 		name:     "maxHeightBoundsRight",
 		env:      environment{"8080", "5", "50", "50", "2000", "10001"},
-		expected: &Settings{0, 0, 0, 0, 0, 0},
+		expected: &Settings{},
 		err: fmt.Errorf("%s: %w", ErrCanNotGetSettings,
 			fmt.Errorf("IMAGE_PREVIEWER_MAX_HEIGHT value must be in range [%d, %d]", minMaxHeight, maxMaxHeight)),
 	},
@@ -175,6 +179,347 @@ func TestParseEnv(t *testing.T) {
 	}
 }
 
+func TestParseEnvCachePersistent(t *testing.T) {
+	setEnv(environment{"8080", "5", "50", "50", "2000", "2000"})
+	defer unsetEnv()
+
+	t.Run("default", func(t *testing.T) {
+		settings := new(Settings)
+		require.NoError(t, settings.ParseEnv())
+		require.False(t, settings.GetCachePersistent())
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		os.Setenv("IMAGE_PREVIEWER_CACHE_PERSISTENT", "true")
+		defer os.Unsetenv("IMAGE_PREVIEWER_CACHE_PERSISTENT")
+
+		settings := new(Settings)
+		require.NoError(t, settings.ParseEnv())
+		require.True(t, settings.GetCachePersistent())
+	})
+
+	t.Run("canNotParse", func(t *testing.T) {
+		os.Setenv("IMAGE_PREVIEWER_CACHE_PERSISTENT", "sometimes")
+		defer os.Unsetenv("IMAGE_PREVIEWER_CACHE_PERSISTENT")
+
+		settings := new(Settings)
+		err := settings.ParseEnv()
+		require.Equal(t, fmt.Errorf("%s: %w", ErrCanNotGetSettings,
+			errors.New("can not parse IMAGE_PREVIEWER_CACHE_PERSISTENT")), err)
+		require.Equal(t, &Settings{}, settings)
+	})
+}
+
+func TestParseEnvCacheBackend(t *testing.T) {
+	setEnv(environment{"8080", "5", "50", "50", "2000", "2000"})
+	defer unsetEnv()
+
+	t.Run("redis", func(t *testing.T) {
+		os.Setenv("IMAGE_PREVIEWER_CACHE_BACKEND", "redis")
+		os.Setenv("IMAGE_PREVIEWER_REDIS_ADDR", "redis:6379")
+		defer os.Unsetenv("IMAGE_PREVIEWER_CACHE_BACKEND")
+		defer os.Unsetenv("IMAGE_PREVIEWER_REDIS_ADDR")
+
+		settings := new(Settings)
+		require.NoError(t, settings.ParseEnv())
+		require.Equal(t, "redis", settings.GetCacheBackend())
+		require.Equal(t, "redis:6379", settings.GetRedisAddr())
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		os.Setenv("IMAGE_PREVIEWER_CACHE_BACKEND", "tape")
+		defer os.Unsetenv("IMAGE_PREVIEWER_CACHE_BACKEND")
+
+		settings := new(Settings)
+		err := settings.ParseEnv()
+		require.Equal(t, fmt.Errorf("%s: %w", ErrCanNotGetSettings,
+			fmt.Errorf("IMAGE_PREVIEWER_CACHE_BACKEND value must be one of %v", cacheBackends)), err)
+		require.Equal(t, &Settings{}, settings)
+	})
+}
+
+func TestParseEnvCachePolicy(t *testing.T) {
+	setEnv(environment{"8080", "5", "50", "50", "2000", "2000"})
+	defer unsetEnv()
+
+	t.Run("arc", func(t *testing.T) {
+		os.Setenv("IMAGE_PREVIEWER_CACHE_POLICY", "arc")
+		defer os.Unsetenv("IMAGE_PREVIEWER_CACHE_POLICY")
+
+		settings := new(Settings)
+		require.NoError(t, settings.ParseEnv())
+		require.Equal(t, "arc", settings.GetCachePolicy())
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		os.Setenv("IMAGE_PREVIEWER_CACHE_POLICY", "random")
+		defer os.Unsetenv("IMAGE_PREVIEWER_CACHE_POLICY")
+
+		settings := new(Settings)
+		err := settings.ParseEnv()
+		require.Equal(t, fmt.Errorf("%s: %w", ErrCanNotGetSettings,
+			fmt.Errorf("IMAGE_PREVIEWER_CACHE_POLICY value must be one of %v", cachePolicies)), err)
+	})
+}
+
+func TestParseEnvCacheShards(t *testing.T) {
+	setEnv(environment{"8080", "5", "50", "50", "2000", "2000"})
+	defer unsetEnv()
+
+	os.Setenv("IMAGE_PREVIEWER_CACHE_SHARDS", "16")
+	defer os.Unsetenv("IMAGE_PREVIEWER_CACHE_SHARDS")
+
+	settings := new(Settings)
+	require.NoError(t, settings.ParseEnv())
+	require.Equal(t, 16, settings.GetCacheShards())
+
+	os.Setenv("IMAGE_PREVIEWER_CACHE_SHARDS", "0")
+	err := settings.ParseEnv()
+	require.Equal(t, fmt.Errorf("%s: %w", ErrCanNotGetSettings,
+		fmt.Errorf("IMAGE_PREVIEWER_CACHE_SHARDS value must be in range [%d, %d]", minShards, maxShards)), err)
+}
+
+func TestParseEnvWarmUp(t *testing.T) {
+	setEnv(environment{"8080", "5", "50", "50", "2000", "2000"})
+	defer unsetEnv()
+
+	os.Setenv("IMAGE_PREVIEWER_WARMUP_FILE", "warmup.txt")
+	os.Setenv("IMAGE_PREVIEWER_WARMUP_CONCURRENCY", "8")
+	defer os.Unsetenv("IMAGE_PREVIEWER_WARMUP_FILE")
+	defer os.Unsetenv("IMAGE_PREVIEWER_WARMUP_CONCURRENCY")
+
+	settings := new(Settings)
+	require.NoError(t, settings.ParseEnv())
+	require.Equal(t, "warmup.txt", settings.GetWarmUpFile())
+	require.Equal(t, 8, settings.GetWarmUpConcurrency())
+
+	os.Setenv("IMAGE_PREVIEWER_WARMUP_CONCURRENCY", "65")
+	err := settings.ParseEnv()
+	require.Equal(t, fmt.Errorf("%s: %w", ErrCanNotGetSettings,
+		fmt.Errorf("IMAGE_PREVIEWER_WARMUP_CONCURRENCY value must be in range [%d, %d]", minWorkers, maxWorkers)), err)
+}
+
+func TestParseEnvPeers(t *testing.T) {
+	setEnv(environment{"8080", "5", "50", "50", "2000", "2000"})
+	defer unsetEnv()
+	defer os.Unsetenv("IMAGE_PREVIEWER_PEERS")
+	defer os.Unsetenv("IMAGE_PREVIEWER_PEER_SELF")
+
+	t.Run("enabled", func(t *testing.T) {
+		os.Setenv("IMAGE_PREVIEWER_PEERS", "http://10.0.0.1:8080/, http://10.0.0.2:8080")
+		os.Setenv("IMAGE_PREVIEWER_PEER_SELF", "http://10.0.0.2:8080")
+
+		settings := new(Settings)
+		require.NoError(t, settings.ParseEnv())
+		require.Equal(t, []string{"http://10.0.0.1:8080", "http://10.0.0.2:8080"}, settings.GetPeers())
+		require.Equal(t, "http://10.0.0.2:8080", settings.GetPeerSelf())
+	})
+
+	t.Run("self is not a peer", func(t *testing.T) {
+		os.Setenv("IMAGE_PREVIEWER_PEERS", "http://10.0.0.1:8080,http://10.0.0.2:8080")
+		os.Setenv("IMAGE_PREVIEWER_PEER_SELF", "http://10.0.0.3:8080")
+
+		settings := new(Settings)
+		err := settings.ParseEnv()
+		require.Equal(t, fmt.Errorf("%s: %w", ErrCanNotGetSettings,
+			errors.New("IMAGE_PREVIEWER_PEER_SELF value must be one of IMAGE_PREVIEWER_PEERS")), err)
+		require.Equal(t, &Settings{}, settings)
+	})
+
+	t.Run("not a URL", func(t *testing.T) {
+		os.Setenv("IMAGE_PREVIEWER_PEERS", "10.0.0.1:8080")
+		os.Setenv("IMAGE_PREVIEWER_PEER_SELF", "10.0.0.1:8080")
+
+		settings := new(Settings)
+		err := settings.ParseEnv()
+		require.Equal(t, fmt.Errorf("%s: %w", ErrCanNotGetSettings,
+			errors.New("IMAGE_PREVIEWER_PEERS value 10.0.0.1:8080 must be a http(s) URL")), err)
+	})
+}
+
+func TestParseEnvDiskWatchdog(t *testing.T) {
+	setEnv(environment{"8080", "5", "50", "50", "2000", "2000"})
+	defer unsetEnv()
+	defer os.Unsetenv("IMAGE_PREVIEWER_DISK_LOW_WATER_MB")
+	defer os.Unsetenv("IMAGE_PREVIEWER_DISK_CRITICAL_MB")
+
+	os.Setenv("IMAGE_PREVIEWER_DISK_LOW_WATER_MB", "1024")
+	os.Setenv("IMAGE_PREVIEWER_DISK_CRITICAL_MB", "256")
+	settings := new(Settings)
+	require.NoError(t, settings.ParseEnv())
+	require.Equal(t, 1024, settings.GetDiskLowWaterMB())
+	require.Equal(t, 256, settings.GetDiskCriticalMB())
+
+	os.Setenv("IMAGE_PREVIEWER_DISK_CRITICAL_MB", "2048")
+	err := settings.ParseEnv()
+	require.Equal(t, fmt.Errorf("%s: %w", ErrCanNotGetSettings,
+		errors.New("IMAGE_PREVIEWER_DISK_CRITICAL_MB value must not exceed IMAGE_PREVIEWER_DISK_LOW_WATER_MB")), err)
+	require.Equal(t, &Settings{}, settings)
+}
+
+func TestParseEnvCacheControl(t *testing.T) {
+	setEnv(environment{"8080", "5", "50", "50", "2000", "2000"})
+	defer unsetEnv()
+
+	settings := new(Settings)
+	require.NoError(t, settings.ParseEnv())
+	require.Equal(t, defaultCacheControl, settings.GetCacheControl())
+
+	os.Setenv("IMAGE_PREVIEWER_CACHE_CONTROL", "private, no-cache")
+	defer os.Unsetenv("IMAGE_PREVIEWER_CACHE_CONTROL")
+	require.NoError(t, settings.ParseEnv())
+	require.Equal(t, "private, no-cache", settings.GetCacheControl())
+}
+
+func TestParseEnvPassHeaders(t *testing.T) {
+	setEnv(environment{"8080", "5", "50", "50", "2000", "2000"})
+	defer unsetEnv()
+	defer os.Unsetenv("IMAGE_PREVIEWER_PASS_HEADERS")
+
+	os.Setenv("IMAGE_PREVIEWER_PASS_HEADERS", "x-request-id, Access-Control-Allow-Origin,")
+	settings := new(Settings)
+	require.NoError(t, settings.ParseEnv())
+	require.Equal(t, []string{"X-Request-Id", "Access-Control-Allow-Origin"}, settings.GetPassHeaders())
+
+	os.Setenv("IMAGE_PREVIEWER_PASS_HEADERS", "X-Request-Id,set-cookie")
+	err := settings.ParseEnv()
+	require.Equal(t, fmt.Errorf("%s: %w", ErrCanNotGetSettings,
+		errors.New("IMAGE_PREVIEWER_PASS_HEADERS value must not contain Set-Cookie")), err)
+}
+
+func TestParseEnvSigningKeys(t *testing.T) {
+	setEnv(environment{"8080", "5", "50", "50", "2000", "2000"})
+	defer unsetEnv()
+	defer os.Unsetenv("IMAGE_PREVIEWER_SIGNING_KEYS")
+
+	settings := new(Settings)
+	require.NoError(t, settings.ParseEnv())
+	require.Empty(t, settings.GetSigningKeys())
+
+	os.Setenv("IMAGE_PREVIEWER_SIGNING_KEYS", "new-secret-key-0001, old-secret-key-0000,")
+	require.NoError(t, settings.ParseEnv())
+	require.Equal(t, []string{"new-secret-key-0001", "old-secret-key-0000"}, settings.GetSigningKeys())
+
+	os.Setenv("IMAGE_PREVIEWER_SIGNING_KEYS", "new-secret-key-0001,short")
+	err := settings.ParseEnv()
+	require.Equal(t, fmt.Errorf("%s: %w", ErrCanNotGetSettings,
+		errors.New("IMAGE_PREVIEWER_SIGNING_KEYS keys must be at least 16 characters long")), err)
+	require.Empty(t, settings.GetSigningKeys())
+}
+
+func TestParseEnvPresets(t *testing.T) {
+	setEnv(environment{"8080", "5", "50", "50", "2000", "2000"})
+	defer unsetEnv()
+	defer os.Unsetenv("IMAGE_PREVIEWER_PRESETS")
+	defer os.Unsetenv("IMAGE_PREVIEWER_PRESETS_ONLY")
+
+	settings := new(Settings)
+	require.NoError(t, settings.ParseEnv())
+	require.Empty(t, settings.GetPresets())
+	require.False(t, settings.GetPresetsOnly())
+
+	os.Setenv("IMAGE_PREVIEWER_PRESETS",
+		`{"thumb": {"mode": "fill", "width": 150, "height": 150, "quality": 80, "crop": "smart"}, "card": {"width": 400, "height": 300, "quality": 0}}`)
+	os.Setenv("IMAGE_PREVIEWER_PRESETS_ONLY", "true")
+	require.NoError(t, settings.ParseEnv())
+	require.Equal(t, map[string]Preset{
+		"thumb": {Mode: "fill", Width: 150, Height: 150, Quality: 80, Crop: "smart"},
+		"card":  {Width: 400, Height: 300},
+	}, settings.GetPresets())
+	require.True(t, settings.GetPresetsOnly())
+
+	for _, tc := range []struct {
+		presets string
+		err     string
+	}{
+		{`{"thumb": {"width": 30, "height": 150}}`,
+			"IMAGE_PREVIEWER_PRESETS preset thumb width value must be in range [50, 2000]"},
+		{`{"thumb": {"width": 150, "height": 3000}}`,
+			"IMAGE_PREVIEWER_PRESETS preset thumb height value must be in range [50, 2000]"},
+		{`{"thumb": {"width": 150, "height": 150, "quality": 101}}`,
+			"IMAGE_PREVIEWER_PRESETS preset thumb quality value must be in range [0, 100], 0 = default"},
+		{`{"thumb": {"width": 150, "height": 150, "crop": "top"}}`,
+			"IMAGE_PREVIEWER_PRESETS preset thumb crop must be one of [center smart]"},
+		{`{"thumb": {"mode": "fit", "width": 150, "height": 150}}`,
+			"IMAGE_PREVIEWER_PRESETS preset thumb mode must be fill"},
+		{`{"a/b": {"width": 150, "height": 150}}`,
+			`IMAGE_PREVIEWER_PRESETS preset name "a/b" must be a path segment`},
+		{`{"a@1": {"width": 150, "height": 150}}`,
+			`IMAGE_PREVIEWER_PRESETS preset name "a@1" must be a path segment`},
+		{`["thumb"]`, "can not parse IMAGE_PREVIEWER_PRESETS"},
+		{"", "IMAGE_PREVIEWER_PRESETS_ONLY requires IMAGE_PREVIEWER_PRESETS"},
+	} {
+		os.Setenv("IMAGE_PREVIEWER_PRESETS", tc.presets)
+		err := settings.ParseEnv()
+		require.Equal(t, fmt.Errorf("%s: %w", ErrCanNotGetSettings, errors.New(tc.err)), err, tc.presets)
+		require.Empty(t, settings.GetPresets())
+		require.False(t, settings.GetPresetsOnly())
+	}
+}
+
+func TestParseEnvForwardHeaders(t *testing.T) {
+	setEnv(environment{"8080", "5", "50", "50", "2000", "2000"})
+	defer unsetEnv()
+	defer os.Unsetenv("IMAGE_PREVIEWER_FORWARD_HEADERS")
+	defer os.Unsetenv("IMAGE_PREVIEWER_ORIGIN_HEADERS")
+
+	t.Run("forward", func(t *testing.T) {
+		os.Setenv("IMAGE_PREVIEWER_FORWARD_HEADERS", "accept,x-trace-id")
+		settings := new(Settings)
+		require.NoError(t, settings.ParseEnv())
+		require.Equal(t, []string{"Accept", "X-Trace-Id"}, settings.GetForwardHeaders())
+
+		os.Setenv("IMAGE_PREVIEWER_FORWARD_HEADERS", "Accept,if-none-match")
+		err := settings.ParseEnv()
+		require.Equal(t, fmt.Errorf("%s: %w", ErrCanNotGetSettings,
+			errors.New("IMAGE_PREVIEWER_FORWARD_HEADERS value must not contain If-None-Match")), err)
+		os.Unsetenv("IMAGE_PREVIEWER_FORWARD_HEADERS")
+	})
+
+	t.Run("origin", func(t *testing.T) {
+		os.Setenv("IMAGE_PREVIEWER_ORIGIN_HEADERS", `{"CDN.example.com": {"authorization": "Bearer secret"}}`)
+		settings := new(Settings)
+		require.NoError(t, settings.ParseEnv())
+		require.Equal(t, map[string]map[string]string{"cdn.example.com": {"Authorization": "Bearer secret"}},
+			settings.GetOriginHeaders())
+
+		os.Setenv("IMAGE_PREVIEWER_ORIGIN_HEADERS", `{"cdn.example.com": {"Host": "other.example.com"}}`)
+		err := settings.ParseEnv()
+		require.Equal(t, fmt.Errorf("%s: %w", ErrCanNotGetSettings,
+			errors.New("IMAGE_PREVIEWER_ORIGIN_HEADERS value must not contain Host")), err)
+
+		os.Setenv("IMAGE_PREVIEWER_ORIGIN_HEADERS", `["cdn.example.com"]`)
+		err = settings.ParseEnv()
+		require.Equal(t, fmt.Errorf("%s: %w", ErrCanNotGetSettings,
+			errors.New("can not parse IMAGE_PREVIEWER_ORIGIN_HEADERS")), err)
+	})
+}
+
+func TestParseEnvCacheHotBytes(t *testing.T) {
+	setEnv(environment{"8080", "5", "50", "50", "2000", "2000"})
+	defer unsetEnv()
+
+	t.Run("enabled", func(t *testing.T) {
+		os.Setenv("IMAGE_PREVIEWER_CACHE_HOT_BYTES", "1048576")
+		defer os.Unsetenv("IMAGE_PREVIEWER_CACHE_HOT_BYTES")
+
+		settings := new(Settings)
+		require.NoError(t, settings.ParseEnv())
+		require.Equal(t, int64(1048576), settings.GetCacheHotBytes())
+	})
+
+	t.Run("bounds", func(t *testing.T) {
+		os.Setenv("IMAGE_PREVIEWER_CACHE_HOT_BYTES", "-1")
+		defer os.Unsetenv("IMAGE_PREVIEWER_CACHE_HOT_BYTES")
+
+		settings := new(Settings)
+		err := settings.ParseEnv()
+		require.Equal(t, fmt.Errorf("%s: %w", ErrCanNotGetSettings,
+			fmt.Errorf("IMAGE_PREVIEWER_CACHE_HOT_BYTES value must be in range [%d, %d]",
+				int64(minHotBytes), int64(maxHotBytes))), err)
+	})
+}
+
 func setEnv(values environment) {
 	os.Setenv("IMAGE_PREVIEWER_PORT", values.port)
 	os.Setenv("IMAGE_PREVIEWER_CACHE_SIZE", values.cacheSize)
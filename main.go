@@ -8,21 +8,34 @@ import (
 
 	internal_cache "github.com/sinuspower/image-previewer/internal/cache"
 	internal_settings "github.com/sinuspower/image-previewer/internal/settings"
-)
-
-var (
-	settings *internal_settings.Settings
-	cache    internal_cache.Cache
+	"github.com/sinuspower/image-previewer/pkg/previewer"
 )
 
 func main() {
-	settings = new(internal_settings.Settings)
+	if len(os.Args) > 1 && os.Args[1] == "sign" {
+		if err := runSign(os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
+	settings := new(internal_settings.Settings)
 	err := settings.ParseEnv()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	cache, err = internal_cache.NewCache(settings.GetCacheSize(), "cache")
+	cache, err := internal_cache.NewStorage(internal_cache.StorageConfig{
+		Backend:    settings.GetCacheBackend(),
+		Capacity:   settings.GetCacheSize(),
+		Path:       "cache",
+		Persistent: settings.GetCachePersistent(),
+		RedisAddr:  settings.GetRedisAddr(),
+		HotBytes:   settings.GetCacheHotBytes(),
+		Policy:     settings.GetCachePolicy(),
+		Shards:     settings.GetCacheShards(),
+	})
 	if err != nil {
 		log.Fatal("can not create cache:", err)
 	}
@@ -43,11 +56,58 @@ func main() {
 			log.Fatal(err)
 		}
 	}()
+
+	options := []previewer.Option{
+		previewer.WithCache(cache),
+		previewer.WithSource(previewer.NewHTTPFetcher(settings.GetOriginHeaders())),
+		previewer.WithLimits(previewer.Limits{
+			MinWidth:  settings.GetMinWidth(),
+			MinHeight: settings.GetMinHeight(),
+			MaxWidth:  settings.GetMaxWidth(),
+			MaxHeight: settings.GetMaxHeight(),
+		}),
+		previewer.WithForwardHeaders(settings.GetForwardHeaders()),
+		previewer.WithPassHeaders(settings.GetPassHeaders()),
+		previewer.WithCacheControl(settings.GetCacheControl()),
+		previewer.WithAdminToken(settings.GetAdminToken()),
+		previewer.WithSigningKeys(settings.GetSigningKeys()...),
+		previewer.WithWarmUpConcurrency(settings.GetWarmUpConcurrency()),
+		previewer.WithDiskWatchdog(uint64(settings.GetDiskLowWaterMB())<<20, uint64(settings.GetDiskCriticalMB())<<20),
+		previewer.WithLogger(log.New(logFile, "", log.LstdFlags)),
+	}
+	if presets := settings.GetPresets(); len(presets) > 0 {
+		options = append(options, previewer.WithPresets(previewerPresets(presets)))
+	}
+	if settings.GetPresetsOnly() {
+		options = append(options, previewer.WithPresetsOnly())
+	}
+	if len(settings.GetPeers()) > 0 {
+		options = append(options, previewer.WithPeers(settings.GetPeerSelf(), settings.GetPeers()))
+	}
+	p, err := previewer.New(options...)
+	if err != nil {
+		log.Fatal("can not create previewer:", err)
+	}
 	log.SetOutput(logFile)
 
-	server := NewServer(settings.GetPort(), settings.GetCacheSize(), logFile)
+	server := NewServer(settings, cache, p, logFile)
 
 	if err := server.ListenAndServe(); err != nil {
 		log.Fatal(err)
 	}
 }
+
+// previewerPresets converts presets of settings to presets of previewer, all of them fill preview.
+func previewerPresets(presets map[string]internal_settings.Preset) map[string]previewer.Preset {
+	converted := make(map[string]previewer.Preset, len(presets))
+	for name, preset := range presets {
+		converted[name] = previewer.Preset{
+			Width:   preset.Width,
+			Height:  preset.Height,
+			Quality: preset.Quality,
+			Crop:    previewer.Crop(preset.Crop),
+		}
+	}
+
+	return converted
+}
@@ -0,0 +1,188 @@
+package previewer
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	internal_cache "github.com/sinuspower/image-previewer/internal/cache"
+)
+
+var (
+	ErrUnauthorized  = errors.New("admin token is missing or invalid")
+	ErrCanNotInspect = errors.New("can not inspect cache")
+	ErrCanNotPurge   = errors.New("can not purge cache")
+	ErrBadPurgeQuery = errors.New("exactly one of url or prefix query parameters is required")
+)
+
+const (
+	defaultEntriesLimit = 100
+	maxEntriesLimit     = 1000
+)
+
+type purgeResponse struct {
+	Purged int `json:"purged"`
+}
+
+type inspectResponse struct {
+	Stats   internal_cache.Stats   `json:"stats"`
+	Total   int                    `json:"total"`
+	Offset  int                    `json:"offset"`
+	Limit   int                    `json:"limit"`
+	Entries []internal_cache.Entry `json:"entries"`
+}
+
+// adminPurgeHandler removes source image and its previews: POST /admin/purge?url={URL}
+// or every entry which path or URL starts with prefix: POST /admin/purge?prefix={prefix}.
+func (p *Previewer) adminPurgeHandler(w http.ResponseWriter, r *http.Request) {
+	fromHost := r.RemoteAddr
+	p.logger.Printf("[INFO] get admin request from %s; %s %s", fromHost, r.Method, r.URL.RequestURI())
+
+	if !p.authorize(w, r) {
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		p.sendResponse(w, http.StatusMethodNotAllowed, nil, fromHost, nil,
+			fmt.Errorf("method %s is not allowed", r.Method))
+
+		return
+	}
+
+	purger, ok := p.cache.(internal_cache.Purger)
+	if !ok {
+		p.sendResponse(w, http.StatusNotImplemented, nil, fromHost, nil,
+			fmt.Errorf("%s: %w", ErrCanNotPurge, internal_cache.ErrNotSupported))
+
+		return
+	}
+
+	source, prefix := r.URL.Query().Get("url"), r.URL.Query().Get("prefix")
+	var purged int
+	var err error
+	switch {
+	case source != "" && prefix == "":
+		purged, err = purger.Purge(normalizeURL(source))
+	case prefix != "" && source == "":
+		purged, err = purger.PurgePrefix(prefix)
+	default:
+		p.sendResponse(w, http.StatusBadRequest, nil, fromHost, nil, ErrBadPurgeQuery)
+
+		return
+	}
+	if err != nil {
+		p.sendResponse(w, http.StatusInternalServerError, nil, fromHost, nil, fmt.Errorf("%s: %w", ErrCanNotPurge, err))
+
+		return
+	}
+
+	p.logger.Printf("[INFO] purged %d cache entries", purged)
+	p.sendJSON(w, fromHost, purgeResponse{purged})
+}
+
+// adminCacheHandler lists cache entries, the most recently used first, and cache stats:
+// GET /admin/cache?offset={offset}&limit={limit}.
+func (p *Previewer) adminCacheHandler(w http.ResponseWriter, r *http.Request) {
+	fromHost := r.RemoteAddr
+	p.logger.Printf("[INFO] get admin request from %s; %s %s", fromHost, r.Method, r.URL.RequestURI())
+
+	if !p.authorize(w, r) {
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		p.sendResponse(w, http.StatusMethodNotAllowed, nil, fromHost, nil,
+			fmt.Errorf("method %s is not allowed", r.Method))
+
+		return
+	}
+
+	inspector, ok := p.cache.(internal_cache.Inspector)
+	if !ok {
+		p.sendResponse(w, http.StatusNotImplemented, nil, fromHost, nil,
+			fmt.Errorf("%s: %w", ErrCanNotInspect, internal_cache.ErrNotSupported))
+
+		return
+	}
+
+	offset, err := getQueryInt(r, "offset", 0, 0, math.MaxInt32)
+	if err != nil {
+		p.sendResponse(w, http.StatusBadRequest, nil, fromHost, nil, fmt.Errorf("%s: %w", ErrCanNotInspect, err))
+
+		return
+	}
+	limit, err := getQueryInt(r, "limit", defaultEntriesLimit, 1, maxEntriesLimit)
+	if err != nil {
+		p.sendResponse(w, http.StatusBadRequest, nil, fromHost, nil, fmt.Errorf("%s: %w", ErrCanNotInspect, err))
+
+		return
+	}
+
+	entries, total := inspector.Entries(offset, limit)
+	p.sendJSON(w, fromHost, inspectResponse{
+		Stats:   inspector.Stats(),
+		Total:   total,
+		Offset:  offset,
+		Limit:   limit,
+		Entries: entries,
+	})
+}
+
+// getQueryInt returns def if query parameter is missing.
+func getQueryInt(r *http.Request, name string, def int, min int, max int) (int, error) {
+	source := r.URL.Query().Get(name)
+	if source == "" {
+		return def, nil
+	}
+
+	value, err := strconv.Atoi(source)
+	if err != nil {
+		return 0, fmt.Errorf("can not parse %s", name)
+	}
+	if value < min || value > max {
+		return 0, fmt.Errorf("%s value must be in range [%d, %d]", name, min, max)
+	}
+
+	return value, nil
+}
+
+// authorize checks bearer token in constant time and writes error response if it does not match.
+// Admin endpoints are disabled if token is not configured.
+func (p *Previewer) authorize(w http.ResponseWriter, r *http.Request) bool {
+	token := p.adminToken
+	if token == "" {
+		http.NotFound(w, r)
+
+		return false
+	}
+
+	given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		p.sendResponse(w, http.StatusUnauthorized, nil, r.RemoteAddr, nil, ErrUnauthorized)
+
+		return false
+	}
+
+	return true
+}
+
+func (p *Previewer) sendJSON(w http.ResponseWriter, toHost string, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		p.sendResponse(w, http.StatusInternalServerError, nil, toHost, nil, err)
+
+		return
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	p.sendResponse(w, http.StatusOK, header, toHost, append(data, '\n'), nil)
+}
@@ -0,0 +1,146 @@
+package previewer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	internal_cache "github.com/sinuspower/image-previewer/internal/cache"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminPurge(t *testing.T) {
+	p := newTestPreviewer(t, WithAdminToken("secret"))
+	defer p.cache.Clear()
+	log.SetOutput(ioutil.Discard)
+
+	imageServer := httptest.NewServer(http.HandlerFunc(imageServerHandleFunc))
+	defer imageServer.Close()
+
+	previewServer := httptest.NewServer(http.HandlerFunc(p.pathHandler))
+	defer previewServer.Close()
+
+	adminServer := httptest.NewServer(http.HandlerFunc(p.adminPurgeHandler))
+	defer adminServer.Close()
+
+	source := imageServer.URL + "/images/source.jpg"
+	for _, size := range []string{"50/50", "100/100"} {
+		rs, err := http.Get(fmt.Sprintf("%s/fill/%s/%s", previewServer.URL, size, source)) //nolint:noctx
+		require.NoError(t, err)
+		rs.Body.Close()
+		require.Equal(t, http.StatusOK, rs.StatusCode)
+	}
+
+	purge := func(query string, token string) (int, string) {
+		rq, err := http.NewRequestWithContext(context.Background(), http.MethodPost, adminServer.URL+query, nil)
+		require.NoError(t, err)
+		rq.Header.Set("Authorization", "Bearer "+token)
+		rs, err := http.DefaultClient.Do(rq)
+		require.NoError(t, err)
+		defer rs.Body.Close()
+		body, err := ioutil.ReadAll(rs.Body)
+		require.NoError(t, err)
+
+		return rs.StatusCode, string(body)
+	}
+
+	t.Run("unauthorized", func(t *testing.T) {
+		status, _ := purge("?url="+source, "wrong")
+		require.Equal(t, http.StatusUnauthorized, status)
+	})
+
+	t.Run("bad query", func(t *testing.T) {
+		status, _ := purge("?url="+source+"&prefix=/fill/", "secret")
+		require.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("by url", func(t *testing.T) {
+		status, body := purge("?url="+source, "secret")
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, "{\"purged\":3}\n", body) // source and two previews
+
+		key := strings.TrimPrefix(source, "http://")
+		for _, path := range []string{source, "/fill/50/50/" + key, "/fill/100/100/" + key} {
+			_, _, ok, err := p.cache.GetFile(path)
+			require.NoError(t, err)
+			require.False(t, ok)
+		}
+	})
+
+	t.Run("by prefix", func(t *testing.T) {
+		rs, err := http.Get(fmt.Sprintf("%s/fill/50/50/%s", previewServer.URL, source)) //nolint:noctx
+		require.NoError(t, err)
+		rs.Body.Close()
+
+		status, body := purge("?prefix="+imageServer.URL+"/images/", "secret")
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, "{\"purged\":2}\n", body)
+	})
+}
+
+func TestAdminDisabled(t *testing.T) {
+	p := newTestPreviewer(t)
+	defer p.cache.Clear()
+	log.SetOutput(ioutil.Discard)
+
+	rq := httptest.NewRequest(http.MethodPost, "/admin/purge?prefix=/", nil)
+	rw := httptest.NewRecorder()
+	p.adminPurgeHandler(rw, rq)
+	require.Equal(t, http.StatusNotFound, rw.Code)
+}
+
+func TestAdminCache(t *testing.T) {
+	p := newTestPreviewer(t, WithAdminToken("secret"))
+	defer p.cache.Clear()
+	log.SetOutput(ioutil.Discard)
+
+	require.NoError(t, p.cache.PutFile("http://a.com/1.jpg", []byte("111"), internal_cache.Metadata{}))
+	require.NoError(t, p.cache.PutFile("http://a.com/2.jpg", []byte("2222"), internal_cache.Metadata{}))
+	_, _, _, err := p.cache.GetFile("http://a.com/1.jpg")
+	require.NoError(t, err)
+	_, _, _, err = p.cache.GetFile("http://a.com/3.jpg")
+	require.NoError(t, err)
+
+	inspect := func(query string) (int, inspectResponse) {
+		rq := httptest.NewRequest(http.MethodGet, "/admin/cache"+query, nil)
+		rq.Header.Set("Authorization", "Bearer secret")
+		rw := httptest.NewRecorder()
+		p.adminCacheHandler(rw, rq)
+
+		response := inspectResponse{}
+		if rw.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &response))
+		}
+
+		return rw.Code, response
+	}
+
+	t.Run("stats", func(t *testing.T) {
+		status, response := inspect("")
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, internal_cache.Stats{Items: 2, Bytes: 7, Hits: 1, Misses: 1}, response.Stats)
+		require.Equal(t, 2, response.Total)
+		require.Equal(t, "http://a.com/1.jpg", response.Entries[0].Path) // the most recently used
+		require.Equal(t, uint64(1), response.Entries[0].Hits)
+		require.Equal(t, int64(3), response.Entries[0].Size)
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		status, response := inspect("?offset=1&limit=1")
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, 2, response.Total)
+		require.Len(t, response.Entries, 1)
+		require.Equal(t, "http://a.com/2.jpg", response.Entries[0].Path)
+	})
+
+	t.Run("bad limit", func(t *testing.T) {
+		status, _ := inspect("?limit=0")
+		require.Equal(t, http.StatusBadRequest, status)
+	})
+}
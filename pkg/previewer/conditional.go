@@ -0,0 +1,78 @@
+package previewer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	internal_cache "github.com/sinuspower/image-previewer/internal/cache"
+)
+
+// previewETag returns strong ETag made from preview bytes.
+func previewETag(image []byte) string {
+	sum := sha256.Sum256(image)
+
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// isConditional reports whether request may be answered with 304 Not Modified.
+func isConditional(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	return r.Header.Get("If-None-Match") != "" || r.Header.Get("If-Modified-Since") != ""
+}
+
+// notModified reports whether client copy is the same as response described by header.
+// If-Modified-Since is ignored if If-None-Match is set (RFC 7232, section 6).
+func notModified(rqHeader http.Header, header http.Header) bool {
+	if list := rqHeader.Get("If-None-Match"); list != "" {
+		return etagMatch(list, header.Get("ETag"))
+	}
+
+	since, err := http.ParseTime(rqHeader.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(header.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+
+	return !modified.After(since)
+}
+
+// etagMatch uses weak comparison as If-None-Match requires.
+func etagMatch(list string, etag string) bool {
+	if etag == "" {
+		return false
+	}
+
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cachedHeader returns response headers of cached preview without reading the preview,
+// if cache is able to describe stored images.
+func (p *Previewer) cachedHeader(path string) (http.Header, bool) {
+	describer, ok := p.cache.(internal_cache.Describer)
+	if !ok {
+		return nil, false
+	}
+
+	meta, ok := describer.Stat(path)
+	if !ok {
+		return nil, false
+	}
+
+	return metadataHeader(meta), true
+}
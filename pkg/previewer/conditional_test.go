@@ -0,0 +1,75 @@
+package previewer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotModified(t *testing.T) {
+	p := newTestPreviewer(t)
+	defer p.cache.Clear()
+	log.SetOutput(ioutil.Discard)
+
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Empty(t, r.Header.Get("If-None-Match")) // client validators are not sent to origin
+		w.Header().Set("Last-Modified", "Thu, 01 Oct 2020 12:00:00 GMT")
+		imageServerHandleFunc(w, r)
+	}))
+	defer imageServer.Close()
+
+	previewServer := httptest.NewServer(http.HandlerFunc(p.pathHandler))
+	defer previewServer.Close()
+
+	get := func(url string, header http.Header) *http.Response {
+		rq, err := http.NewRequest(http.MethodGet, url, nil) //nolint:noctx
+		require.NoError(t, err)
+		rq.Header = header
+		rs, err := http.DefaultClient.Do(rq)
+		require.NoError(t, err)
+		defer rs.Body.Close()
+		body, err := ioutil.ReadAll(rs.Body)
+		require.NoError(t, err)
+		if rs.StatusCode == http.StatusNotModified {
+			require.Empty(t, body)
+		}
+
+		return rs
+	}
+
+	url := fmt.Sprintf("%s/fill/50/50/%s/images/source.jpg", previewServer.URL, imageServer.URL)
+	rs := get(url, http.Header{"If-None-Match": {`"unknown"`}}) // miss renders preview
+	require.Equal(t, http.StatusOK, rs.StatusCode)
+	etag := rs.Header.Get("ETag")
+	require.NotEmpty(t, etag)
+
+	for _, tc := range []struct {
+		name   string
+		header http.Header
+		status int
+	}{
+		{"matching etag", http.Header{"If-None-Match": {`"other", ` + etag}}, http.StatusNotModified},
+		{"weak etag", http.Header{"If-None-Match": {"W/" + etag}}, http.StatusNotModified},
+		{"any etag", http.Header{"If-None-Match": {"*"}}, http.StatusNotModified},
+		{"other etag", http.Header{"If-None-Match": {`"other"`}}, http.StatusOK},
+		{"not modified since", http.Header{"If-Modified-Since": {"Fri, 02 Oct 2020 12:00:00 GMT"}}, http.StatusNotModified},
+		{"modified since", http.Header{"If-Modified-Since": {"Wed, 30 Sep 2020 12:00:00 GMT"}}, http.StatusOK},
+		{"etag first", http.Header{
+			"If-None-Match":     {`"other"`},
+			"If-Modified-Since": {"Fri, 02 Oct 2020 12:00:00 GMT"},
+		}, http.StatusOK},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			rs := get(url, tc.header)
+			require.Equal(t, tc.status, rs.StatusCode)
+			require.Equal(t, etag, rs.Header.Get("ETag"))
+			require.Equal(t, "public, max-age=86400", rs.Header.Get("Cache-Control"))
+		})
+	}
+}
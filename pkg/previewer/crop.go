@@ -0,0 +1,93 @@
+package previewer
+
+import (
+	"image"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+// Crop selects part of scaled source image which fills preview.
+type Crop string
+
+const (
+	CropCenter Crop = "center" // default
+	CropSmart  Crop = "smart"  // the most detailed part, i.e. subject rather than plain background
+)
+
+// smartFill scales image to cover preview and crops its most detailed part along the side which exceeds preview.
+// Details are measured as brightness differences between neighbour pixels.
+func smartFill(img image.Image, width int, height int) image.Image {
+	bounds := img.Bounds()
+	scale := math.Max(float64(width)/float64(bounds.Dx()), float64(height)/float64(bounds.Dy()))
+	scaledWidth := int(math.Round(float64(bounds.Dx()) * scale))
+	if scaledWidth < width {
+		scaledWidth = width
+	}
+	scaledHeight := int(math.Round(float64(bounds.Dy()) * scale))
+	if scaledHeight < height {
+		scaledHeight = height
+	}
+	scaled := imaging.Resize(img, scaledWidth, scaledHeight, imaging.Lanczos)
+
+	columns, rows := details(scaled)
+	x, y := bestWindow(columns, width), bestWindow(rows, height)
+
+	return imaging.Crop(scaled, image.Rect(x, y, x+width, y+height))
+}
+
+// details returns sums of brightness differences of every pixel with its right and bottom neighbours
+// by columns and by rows.
+func details(img *image.NRGBA) ([]int, []int) {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	brightness := func(x int, y int) int {
+		i := img.PixOffset(x, y)
+
+		return (299*int(img.Pix[i]) + 587*int(img.Pix[i+1]) + 114*int(img.Pix[i+2])) / 1000
+	}
+
+	columns, rows := make([]int, width), make([]int, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			b, diff := brightness(x, y), 0
+			if x+1 < width {
+				diff += abs(brightness(x+1, y) - b)
+			}
+			if y+1 < height {
+				diff += abs(brightness(x, y+1) - b)
+			}
+			columns[x] += diff
+			rows[y] += diff
+		}
+	}
+
+	return columns, rows
+}
+
+// bestWindow returns start of window of given size with the most details, the window closest to center wins a tie,
+// so plain images are cropped at center.
+func bestWindow(details []int, size int) int {
+	center := (len(details) - size) / 2
+	sum := 0
+	for i := 0; i < size; i++ {
+		sum += details[i]
+	}
+
+	best, bestSum := 0, sum
+	for start := 1; start+size <= len(details); start++ {
+		sum += details[start+size-1] - details[start-1]
+		if sum > bestSum || (sum == bestSum && abs(start-center) < abs(best-center)) {
+			best, bestSum = start, sum
+		}
+	}
+
+	return best
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+
+	return value
+}
@@ -0,0 +1,49 @@
+package previewer
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBestWindow(t *testing.T) {
+	require.Equal(t, 3, bestWindow([]int{0, 0, 0, 5, 9, 0}, 2))
+	require.Equal(t, 2, bestWindow([]int{1, 1, 1, 1, 1, 1, 1}, 3)) // plain image is cropped at center
+	require.Equal(t, 0, bestWindow([]int{4, 2}, 2))
+}
+
+func TestSmartFill(t *testing.T) {
+	// white 400x100 image with checkerboard at its right part
+	source := image.NewNRGBA(image.Rect(0, 0, 400, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 400; x++ {
+			c := color.White
+			if x >= 300 && (x/10+y/10)%2 == 0 {
+				c = color.Black
+			}
+			source.Set(x, y, c)
+		}
+	}
+
+	dark := func(img image.Image) int {
+		count := 0
+		for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+			for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+				if r, _, _, _ := img.At(x, y).RGBA(); r < 0x8000 {
+					count++
+				}
+			}
+		}
+
+		return count
+	}
+
+	preview := smartFill(source, 50, 50)
+	require.Equal(t, image.Rect(0, 0, 50, 50), preview.Bounds())
+	require.Greater(t, dark(preview), 500) // checkerboard is half dark
+
+	plain := smartFill(image.NewNRGBA(image.Rect(0, 0, 400, 100)), 100, 200)
+	require.Equal(t, image.Rect(0, 0, 100, 200), plain.Bounds())
+}
@@ -0,0 +1,168 @@
+package previewer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"strconv"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// Processor makes preview from source image as described by preset.
+type Processor interface {
+	Process(source []byte, preset Preset) ([]byte, error)
+}
+
+// Cutter fills preview with source image scaled and cropped at center or at its most detailed part.
+type Cutter struct{}
+
+var ErrCanNotParsePath = errors.New("can not parse path")
+
+func NewCutter() *Cutter {
+	return &Cutter{}
+}
+
+func (c *Cutter) Process(source []byte, preset Preset) ([]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(source))
+	if err != nil {
+		return nil, err
+	}
+
+	var preview image.Image
+	if preset.Crop == CropSmart {
+		preview = smartFill(img, preset.Width, preset.Height)
+	} else {
+		preview = imaging.Fill(img, preset.Width, preset.Height, imaging.Center, imaging.Lanczos)
+	}
+
+	var options *jpeg.Options // default quality of encoder
+	if preset.Quality > 0 {
+		options = &jpeg.Options{Quality: preset.Quality}
+	}
+	buffer := new(bytes.Buffer)
+	err = jpeg.Encode(buffer, preview, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// Limits are bounds of preview dimensions, inclusive.
+type Limits struct {
+	MinWidth  int
+	MinHeight int
+	MaxWidth  int
+	MaxHeight int
+}
+
+// DefaultLimits are used if no limits are given to New.
+var DefaultLimits = Limits{MinWidth: 1, MinHeight: 1, MaxWidth: 2000, MaxHeight: 2000}
+
+// check returns error if preview dimensions are out of limits.
+func (l Limits) check(width int, height int) error {
+	if width < l.MinWidth || width > l.MaxWidth {
+		return fmt.Errorf("width value must be in range [%d, %d]", l.MinWidth, l.MaxWidth)
+	}
+	if height < l.MinHeight || height > l.MaxHeight {
+		return fmt.Errorf("height value must be in range [%d, %d]", l.MinHeight, l.MaxHeight)
+	}
+
+	return nil
+}
+
+// parsePath returns width, height and URL from input string like /fill/300/200/{URL}.
+// Dimensions are checked against limits.
+func parsePath(path string, limits Limits) (int, int, string, error) {
+	parts := strings.SplitN(path, "/", 5)
+
+	if len(parts) < 5 {
+		return 0, 0, "", fmt.Errorf("%s: %w", ErrCanNotParsePath,
+			errors.New("missing expected elements in URL"))
+	}
+
+	width, err := getWidth(parts[2], limits.MinWidth, limits.MaxWidth)
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("%s: %w", ErrCanNotParsePath, err)
+	}
+
+	height, err := getHeight(parts[3], limits.MinHeight, limits.MaxHeight)
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("%s: %w", ErrCanNotParsePath, err)
+	}
+
+	url, err := getURL(parts[4])
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("%s: %w", ErrCanNotParsePath, err)
+	}
+
+	return width, height, url, nil
+}
+
+func getWidth(source string, min int, max int) (int, error) {
+	width, err := strconv.Atoi(source)
+	if err != nil {
+		return 0, errors.New("can not get width")
+	}
+
+	if width < min || width > max {
+		return 0, fmt.Errorf("width value must be in range [%d, %d]", min, max)
+	}
+
+	return width, nil
+}
+
+func getHeight(source string, min int, max int) (int, error) {
+	height, err := strconv.Atoi(source)
+	if err != nil {
+		return 0, errors.New("can not get height")
+	}
+
+	if height < min || height > max {
+		return 0, fmt.Errorf("height value must be in range [%d, %d]", min, max)
+	}
+
+	return height, nil
+}
+
+func getURL(source string) (string, error) {
+	if scheme := getScheme(source); scheme != "" && scheme != "http" {
+		return "", fmt.Errorf("scheme %s is not supported, source URL must be http", scheme)
+	}
+	path := strings.SplitN(source, "?", 2)[0] // extension of query style URL is followed by its query
+	if !strings.HasSuffix(path, "jpg") && !strings.HasSuffix(path, "jpeg") {
+		return "", errors.New("file extension must be jpg or jpeg")
+	}
+
+	return normalizeURL(source), nil
+}
+
+// getScheme returns lowercased scheme of source URL or empty string if URL has no scheme.
+func getScheme(source string) string {
+	i := strings.Index(source, "://")
+	if i <= 0 {
+		return ""
+	}
+	for _, r := range source[:i] {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '+' && r != '-' && r != '.' {
+			return "" // "://" is a part of path or query
+		}
+	}
+
+	return strings.ToLower(source[:i])
+}
+
+// normalizeURL adds scheme to source image URL if it is missing and lowercases it otherwise,
+// so URLs of the same image have the same cache key.
+func normalizeURL(source string) string {
+	scheme := getScheme(source)
+	if scheme == "" {
+		return "http://" + source
+	}
+
+	return scheme + source[len(scheme):]
+}
@@ -0,0 +1,145 @@
+package previewer_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/sinuspower/image-previewer/pkg/previewer"
+)
+
+// sourceImage returns JPEG image of given size.
+func sourceImage(width int, height int) []byte {
+	buffer := new(bytes.Buffer)
+	if err := jpeg.Encode(buffer, image.NewRGBA(image.Rect(0, 0, width, height)), nil); err != nil {
+		panic(err)
+	}
+
+	return buffer.Bytes()
+}
+
+// memorySource is a Fetcher which keeps source images in memory.
+type memorySource map[string][]byte
+
+func (s memorySource) Fetch(source string, header http.Header) ([]byte, http.Header, error) {
+	image, ok := s[source]
+	if !ok {
+		return nil, nil, errors.New("no such image")
+	}
+
+	return image, http.Header{"Content-Type": {"image/jpeg"}}, nil
+}
+
+func ExamplePreviewer_Transform() {
+	p, err := previewer.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	preview, err := p.Transform(sourceImage(400, 200), 100, 100)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	config, err := jpeg.DecodeConfig(bytes.NewReader(preview))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%dx%d\n", config.Width, config.Height)
+
+	_, err = p.Transform(sourceImage(400, 200), 5000, 100)
+	fmt.Println(err)
+	// Output:
+	// 100x100
+	// width value must be in range [1, 2000]
+}
+
+func ExampleWithSource() {
+	log.SetOutput(ioutil.Discard)
+	p, err := previewer.New(
+		previewer.WithSource(memorySource{"memory://photo.jpg": sourceImage(640, 480)}),
+		previewer.WithLimits(previewer.Limits{MinWidth: 16, MinHeight: 16, MaxWidth: 320, MaxHeight: 320}),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	source, _, err := p.Fetch("memory://photo.jpg", http.Header{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	preview, err := p.Transform(source, 320, 240)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	config, err := jpeg.DecodeConfig(bytes.NewReader(preview))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%dx%d\n", config.Width, config.Height)
+	// Output: 320x240
+}
+
+func ExampleNewDiskCache() {
+	log.SetOutput(ioutil.Discard)
+	cache, err := previewer.NewDiskCache(10, "cache_example")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer cache.Clear()
+
+	p, err := previewer.New(
+		previewer.WithCache(cache),
+		previewer.WithSource(memorySource{"memory://photo.jpg": sourceImage(640, 480)}),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if _, _, err := p.Fetch("memory://photo.jpg", http.Header{}); err != nil {
+		log.Fatal(err)
+	}
+	_, _, ok, err := cache.GetFile("memory://photo.jpg")
+	fmt.Println(ok, err)
+	// Output: true <nil>
+}
+
+func ExamplePreviewer_ServeHTTP() {
+	log.SetOutput(ioutil.Discard)
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		_, _ = w.Write(sourceImage(1024, 504))
+	}))
+	defer origin.Close()
+
+	p, err := previewer.New(previewer.WithCacheControl("public, max-age=60"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	server := httptest.NewServer(p)
+	defer server.Close()
+
+	rs, err := http.Get(server.URL + "/fill/300/200/" + strings.TrimPrefix(origin.URL, "http://") + "/gopher.jpg")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer rs.Body.Close()
+
+	config, err := jpeg.DecodeConfig(rs.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(rs.StatusCode, rs.Header.Get("Content-Type"), rs.Header.Get("Cache-Control"))
+	fmt.Printf("%dx%d\n", config.Width, config.Height)
+	// Output:
+	// 200 image/jpeg public, max-age=60
+	// 300x200
+}
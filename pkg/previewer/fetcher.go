@@ -0,0 +1,68 @@
+package previewer
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Fetcher loads source image, header are headers of client request allowed to be sent to origin.
+type Fetcher interface {
+	Fetch(source string, header http.Header) ([]byte, http.Header, error)
+}
+
+// HTTPFetcher loads source images from their origins by HTTP.
+type HTTPFetcher struct {
+	client        *http.Client
+	originHeaders map[string]map[string]string // extra headers by origin host
+}
+
+func NewHTTPFetcher(originHeaders map[string]map[string]string) *HTTPFetcher {
+	return &HTTPFetcher{
+		client:        &http.Client{},
+		originHeaders: originHeaders,
+	}
+}
+
+func (f *HTTPFetcher) Fetch(source string, header http.Header) ([]byte, http.Header, error) {
+	rq, err := http.NewRequestWithContext(context.Background(), "GET", source, nil)
+	if err != nil {
+		return nil, header, err
+	}
+
+	rq.Header = f.withOriginHeaders(header, source)
+
+	rs, err := f.client.Do(rq)
+	if err != nil {
+		return nil, header, err
+	}
+	defer rs.Body.Close()
+
+	bytes, err := ioutil.ReadAll(rs.Body)
+	if err != nil {
+		return nil, header, err
+	}
+
+	return bytes, rs.Header.Clone(), nil
+}
+
+// withOriginHeaders returns copy of header with headers configured for host of source URL added or overridden.
+func (f *HTTPFetcher) withOriginHeaders(header http.Header, source string) http.Header {
+	u, err := url.Parse(source)
+	if err != nil {
+		return header
+	}
+	extra, ok := f.originHeaders[strings.ToLower(u.Host)]
+	if !ok {
+		return header
+	}
+
+	header = header.Clone()
+	for name, value := range extra {
+		header.Set(name, value)
+	}
+
+	return header
+}
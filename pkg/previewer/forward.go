@@ -0,0 +1,71 @@
+package previewer
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// viaName identifies previewer in Via header of requests to origins.
+const viaName = "image-previewer"
+
+// hopByHopHeaders describe a single connection (RFC 7230, section 6.1), they are never sent to origins.
+var hopByHopHeaders = []string{"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization",
+	"Proxy-Connection", "Te", "Trailer", "Transfer-Encoding", "Upgrade"}
+
+// isHopByHop reports whether header is one of hopByHopHeaders, name must be canonical.
+func isHopByHop(name string) bool {
+	for _, h := range hopByHopHeaders {
+		if name == h {
+			return true
+		}
+	}
+
+	return false
+}
+
+// forwardableHeaders returns canonical names without hop-by-hop headers.
+func forwardableHeaders(names []string) []string {
+	forwardable := []string{}
+	for _, name := range names {
+		if name = http.CanonicalHeaderKey(name); !isHopByHop(name) {
+			forwardable = append(forwardable, name)
+		}
+	}
+
+	return forwardable
+}
+
+// forwardHeader returns headers of client request which may be sent to origin of source image.
+// Only headers listed in names are kept, hop-by-hop headers and ones named by Connection are dropped even if listed.
+// X-Forwarded-For and Via are extended with this hop.
+func forwardHeader(r *http.Request, names []string) http.Header {
+	hopByHop := map[string]bool{}
+	for _, value := range r.Header.Values("Connection") {
+		for _, name := range strings.Split(value, ",") {
+			hopByHop[http.CanonicalHeaderKey(strings.TrimSpace(name))] = true
+		}
+	}
+
+	header := http.Header{}
+	for _, name := range forwardableHeaders(names) {
+		if values := r.Header.Values(name); len(values) > 0 && !hopByHop[name] {
+			header[name] = values
+		}
+	}
+
+	client := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		client = host
+	}
+	header.Set("X-Forwarded-For", appendHop(r.Header.Values("X-Forwarded-For"), client))
+	header.Set("Via", appendHop(r.Header.Values("Via"), fmt.Sprintf("%d.%d %s", r.ProtoMajor, r.ProtoMinor, viaName)))
+
+	return header
+}
+
+// appendHop adds hop to comma-separated list of previous hops.
+func appendHop(previous []string, hop string) string {
+	return strings.Join(append(previous, hop), ", ")
+}
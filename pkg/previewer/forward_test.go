@@ -0,0 +1,75 @@
+package previewer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestForwardHeaders(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+
+	var received http.Header
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		data, err := ioutil.ReadFile("../../test/testdata/_gopher_original_1024x504.jpg")
+		require.NoError(t, err)
+		_, _ = w.Write(data)
+	}))
+	defer imageServer.Close()
+	origin := strings.TrimPrefix(imageServer.URL, "http://")
+	p := newTestPreviewer(t,
+		WithForwardHeaders([]string{"Accept", "X-Drop"}),
+		WithSource(NewHTTPFetcher(map[string]map[string]string{origin: {"Authorization": "Bearer origin"}})))
+	defer p.cache.Clear()
+
+	previewServer := httptest.NewServer(http.HandlerFunc(p.pathHandler))
+	defer previewServer.Close()
+
+	rq, err := http.NewRequest(http.MethodGet, //nolint:noctx
+		fmt.Sprintf("%s/fill/50/50/%s/images/source.jpg", previewServer.URL, origin), nil)
+	require.NoError(t, err)
+	rq.Header.Set("Accept", "image/*")
+	rq.Header.Set("Cookie", "session=client")
+	rq.Header.Set("Authorization", "Bearer client")
+	rq.Header.Set("If-None-Match", `"client"`)
+	rq.Header.Set("Connection", "X-Drop")
+	rq.Header.Set("X-Drop", "hop-by-hop")
+	rq.Header.Set("X-Forwarded-For", "10.0.0.1")
+	rq.Header.Set("Via", "1.1 proxy")
+
+	rs, err := http.DefaultClient.Do(rq)
+	require.NoError(t, err)
+	rs.Body.Close()
+	require.Equal(t, http.StatusOK, rs.StatusCode)
+
+	require.Equal(t, "image/*", received.Get("Accept"))
+	require.Equal(t, "Bearer origin", received.Get("Authorization")) // configured for origin
+	require.Empty(t, received.Get("Cookie"))
+	require.Empty(t, received.Get("If-None-Match"))
+	require.Empty(t, received.Get("X-Drop"))
+	require.Equal(t, "10.0.0.1, 127.0.0.1", received.Get("X-Forwarded-For"))
+	require.Equal(t, "1.1 proxy, 1.1 image-previewer", received.Get("Via"))
+}
+
+func TestForwardHopByHop(t *testing.T) {
+	p, err := New(WithForwardHeaders([]string{"accept", "upgrade", "Proxy-Authorization", "TE", "X-Trace-Id"}))
+	require.NoError(t, err)
+	require.Equal(t, []string{"Accept", "X-Trace-Id"}, p.forwardHeaders)
+
+	rq := httptest.NewRequest(http.MethodGet, "/fill/50/50/example.com/a.jpg", nil)
+	rq.Header.Set("Accept", "image/*")
+	rq.Header.Set("Keep-Alive", "timeout=5")
+	rq.Header.Set("Transfer-Encoding", "chunked")
+
+	header := forwardHeader(rq, []string{"Accept", "keep-alive", "Transfer-Encoding"})
+	require.Equal(t, "image/*", header.Get("Accept"))
+	require.Empty(t, header.Get("Keep-Alive"))
+	require.Empty(t, header.Get("Transfer-Encoding"))
+}
@@ -0,0 +1,67 @@
+package previewer
+
+import (
+	"net/http"
+	"time"
+
+	internal_cache "github.com/sinuspower/image-previewer/internal/cache"
+)
+
+const watchdogInterval = 10 * time.Second
+
+type healthResponse struct {
+	Status string                         `json:"status"` // ok or degraded
+	Disk   *internal_cache.WatchdogStatus `json:"disk,omitempty"`
+}
+
+// healthHandler reports whether service works normally: GET /health. Service is degraded
+// while free space on cache volume is low, previews are still served then.
+func (p *Previewer) healthHandler(w http.ResponseWriter, r *http.Request) {
+	response := healthResponse{Status: "ok"}
+	if p.watchdog != nil {
+		status := p.watchdog.Status()
+		response.Disk = &status
+		if status.Level != internal_cache.DiskOK {
+			response.Status = "degraded"
+		}
+	}
+
+	p.sendJSON(w, r.RemoteAddr, response)
+}
+
+// runWatchdog checks free space on cache volume until stop is closed and logs changes of its level.
+func (p *Previewer) runWatchdog(stop <-chan struct{}) {
+	ticker := time.NewTicker(watchdogInterval)
+	defer ticker.Stop()
+
+	level := internal_cache.DiskOK
+	for {
+		status := p.watchdog.Check()
+		switch {
+		case status.Error != "":
+			p.logger.Println("[WARN] can not check free disk space:", status.Error)
+		case status.Level != level || status.Level != internal_cache.DiskOK:
+			p.logDiskLevel(status)
+		}
+		level = status.Level
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Previewer) logDiskLevel(status internal_cache.WatchdogStatus) {
+	switch status.Level {
+	case internal_cache.DiskCritical:
+		p.logger.Printf("[ERROR] free disk space is critical: %d bytes, caching stopped; %d entries evicted",
+			status.Free, status.Evicted)
+	case internal_cache.DiskLow:
+		p.logger.Printf("[WARN] free disk space is low, cache evicted: %d bytes free; %d entries evicted",
+			status.Free, status.Evicted)
+	default:
+		p.logger.Printf("[INFO] free disk space is ok: %d bytes, caching resumed", status.Free)
+	}
+}
@@ -0,0 +1,60 @@
+package previewer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	internal_cache "github.com/sinuspower/image-previewer/internal/cache"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealth(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+
+	health := func(p *Previewer) healthResponse {
+		rw := httptest.NewRecorder()
+		p.healthHandler(rw, httptest.NewRequest(http.MethodGet, "/health", nil))
+		require.Equal(t, http.StatusOK, rw.Code)
+
+		response := healthResponse{}
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &response))
+
+		return response
+	}
+
+	t.Run("not watched", func(t *testing.T) {
+		p := newTestPreviewer(t)
+		defer p.cache.Clear()
+
+		response := health(p)
+		require.Equal(t, "ok", response.Status)
+		require.Nil(t, response.Disk)
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		p := newTestPreviewer(t, WithDiskWatchdog(1, 0))
+		defer p.cache.Clear()
+		p.watchdog.Check()
+
+		response := health(p)
+		require.Equal(t, "ok", response.Status)
+		require.Equal(t, internal_cache.DiskOK, response.Disk.Level)
+		require.True(t, response.Disk.Caching)
+	})
+
+	t.Run("critical", func(t *testing.T) {
+		p := newTestPreviewer(t, WithDiskWatchdog(0, 1<<62))
+		defer p.cache.Clear()
+		p.watchdog.Check()
+
+		response := health(p)
+		require.Equal(t, "degraded", response.Status)
+		require.Equal(t, internal_cache.DiskCritical, response.Disk.Level)
+		require.False(t, response.Disk.Caching)
+		require.False(t, response.Disk.Triggered.IsZero())
+	})
+}
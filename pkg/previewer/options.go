@@ -0,0 +1,153 @@
+package previewer
+
+import (
+	"io/ioutil"
+	"log"
+
+	internal_cache "github.com/sinuspower/image-previewer/internal/cache"
+	internal_peers "github.com/sinuspower/image-previewer/internal/peers"
+)
+
+// Cache stores source images and previews by URL and path.
+type Cache = internal_cache.Storage
+
+// Metadata describes image stored in Cache.
+type Metadata = internal_cache.Metadata
+
+// NewMemoryCache returns Cache which keeps up to capacity images in memory.
+func NewMemoryCache(capacity int) Cache {
+	return internal_cache.NewMemoryStorage(capacity)
+}
+
+// NewDiskCache returns Cache which keeps up to capacity images in files under dir, dir is created if it is missing.
+func NewDiskCache(capacity int, dir string) (Cache, error) {
+	cache, err := internal_cache.NewFileCache(capacity, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return cache, nil
+}
+
+const (
+	defaultCacheSize         = 100 // images kept in memory if no cache is given
+	defaultCacheControl      = "public, max-age=86400"
+	defaultWarmUpConcurrency = 4
+)
+
+var defaultForwardHeaders = []string{"Accept", "Accept-Language", "User-Agent"}
+
+// Option configures Previewer.
+type Option func(*Previewer)
+
+// WithCache sets storage of source images and previews, in-memory cache is used by default.
+func WithCache(cache Cache) Option {
+	return func(p *Previewer) {
+		p.cache = cache
+	}
+}
+
+// WithSource sets fetcher of source images, HTTPFetcher is used by default.
+func WithSource(fetcher Fetcher) Option {
+	return func(p *Previewer) {
+		p.fetcher = fetcher
+	}
+}
+
+// WithProcessor sets transformation of source images, Cutter is used by default.
+func WithProcessor(processor Processor) Option {
+	return func(p *Previewer) {
+		p.processor = processor
+	}
+}
+
+// WithLimits sets bounds of preview dimensions, DefaultLimits are used by default.
+func WithLimits(limits Limits) Option {
+	return func(p *Previewer) {
+		p.limits = limits
+	}
+}
+
+// WithForwardHeaders sets headers of client requests which are sent to origins, hop-by-hop headers are never sent.
+func WithForwardHeaders(names []string) Option {
+	return func(p *Previewer) {
+		p.forwardHeaders = forwardableHeaders(names)
+	}
+}
+
+// WithPassHeaders sets headers of origin responses which are sent to clients along with own headers of preview.
+func WithPassHeaders(names []string) Option {
+	return func(p *Previewer) {
+		p.passHeaders = names
+	}
+}
+
+// WithCacheControl sets Cache-Control header of previews, empty value disables it.
+func WithCacheControl(value string) Option {
+	return func(p *Previewer) {
+		p.cacheControl = value
+	}
+}
+
+// WithAdminToken enables admin endpoints protected by bearer token.
+func WithAdminToken(token string) Option {
+	return func(p *Previewer) {
+		p.adminToken = token
+	}
+}
+
+// WithLogger sets logger of requests and cache operations, the standard logger is used by default.
+// Nil logger disables logging.
+func WithLogger(logger *log.Logger) Option {
+	return func(p *Previewer) {
+		if logger == nil {
+			logger = log.New(ioutil.Discard, "", 0)
+		}
+		p.logger = logger
+	}
+}
+
+// WithWarmUpConcurrency sets how many previews warm-up renders at once, it must be positive.
+func WithWarmUpConcurrency(concurrency int) Option {
+	return func(p *Previewer) {
+		p.warmUpConcurrency = concurrency
+	}
+}
+
+// WithSigningKeys requires signed preview URLs, see SignURL. The first key is used to sign requests to peers,
+// all keys are accepted, so the new key goes first while the old one is rotated out.
+func WithSigningKeys(keys ...string) Option {
+	return func(p *Previewer) {
+		p.signingKeys = keys
+	}
+}
+
+// WithPresets sets named presets served by /preset/{name}/{URL}, they must fit limits.
+func WithPresets(presets map[string]Preset) Option {
+	return func(p *Previewer) {
+		p.presets = presets
+	}
+}
+
+// WithPresetsOnly rejects previews of raw dimensions, so clients may request presets only.
+func WithPresetsOnly() Option {
+	return func(p *Previewer) {
+		p.presetsOnly = true
+	}
+}
+
+// WithPeers shares rendering of previews with other instances, self is URL of this instance among peers.
+func WithPeers(self string, peers []string) Option {
+	return func(p *Previewer) {
+		p.peers = internal_peers.NewPool(self, peers)
+	}
+}
+
+// WithDiskWatchdog evicts cache if free space on cache volume is below lowWater
+// and stops caching below critical, both are in bytes.
+func WithDiskWatchdog(lowWater uint64, critical uint64) Option {
+	return func(p *Previewer) {
+		p.diskLowWater = lowWater
+		p.diskCritical = critical
+	}
+}
@@ -0,0 +1,114 @@
+package previewer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	internal_peers "github.com/sinuspower/image-previewer/internal/peers"
+	"github.com/stretchr/testify/require"
+)
+
+// previewInstance is a previewer listening on loopback which counts requests from its peers.
+type previewInstance struct {
+	server       *httptest.Server
+	previewer    *Previewer // set when URLs of all peers are known
+	peerRequests int64
+}
+
+func newPreviewInstance() *previewInstance {
+	instance := &previewInstance{}
+	instance.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, internal_peers.PathPrefix+"/") {
+			atomic.AddInt64(&instance.peerRequests, 1)
+		}
+		instance.previewer.ServeHTTP(w, r)
+	}))
+
+	return instance
+}
+
+func TestPeers(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+
+	imageServer := httptest.NewServer(http.HandlerFunc(imageServerHandleFunc))
+	defer imageServer.Close()
+
+	origin := strings.TrimPrefix(imageServer.URL, "http://") // server mux cleans double slashes in paths
+	instances := []*previewInstance{newPreviewInstance(), newPreviewInstance(), newPreviewInstance()}
+	urls := []string{}
+	for _, instance := range instances {
+		defer instance.server.Close()
+		urls = append(urls, instance.server.URL)
+	}
+	for i, instance := range instances { // every instance has its own cache and knows itself in pool
+		instance.previewer = newTestPreviewerWithCache(t, fmt.Sprintf("cache_peer_%d", i),
+			WithPeers(instance.server.URL, urls))
+		defer instance.previewer.cache.Clear()
+	}
+	self := instances[0]
+	peers := self.previewer.peers
+
+	// pathOwnedBy returns preview path not requested yet which is owned by instance with given URL.
+	size := 50
+	pathOwnedBy := func(url string) string {
+		for ; size < 2000; size++ {
+			path := fmt.Sprintf("/fill/%d/%d/%s/images/source.jpg", size, size, origin)
+			if owner, _ := peers.Owner(path); owner == url {
+				size++
+
+				return path
+			}
+		}
+		t.Fatal("no path owned by", url)
+
+		return ""
+	}
+
+	get := func(t *testing.T, path string) {
+		rs, err := http.Get(self.server.URL + path) //nolint:noctx
+		require.NoError(t, err)
+		defer rs.Body.Close()
+		body, err := ioutil.ReadAll(rs.Body)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rs.StatusCode, string(body))
+	}
+
+	t.Run("owned by self", func(t *testing.T) {
+		get(t, pathOwnedBy(self.server.URL))
+		for _, instance := range instances {
+			require.Equal(t, int64(0), atomic.LoadInt64(&instance.peerRequests))
+		}
+	})
+
+	t.Run("owned by peer", func(t *testing.T) {
+		owner := instances[1]
+		get(t, pathOwnedBy(owner.server.URL))
+		require.Equal(t, int64(1), atomic.LoadInt64(&owner.peerRequests))
+		require.Equal(t, int64(0), atomic.LoadInt64(&self.peerRequests))
+	})
+
+	t.Run("peer is down", func(t *testing.T) {
+		owner := instances[2]
+		path := pathOwnedBy(owner.server.URL)
+		owner.server.Close()
+
+		get(t, path) // rendered locally
+		require.Equal(t, int64(0), atomic.LoadInt64(&owner.peerRequests))
+	})
+
+	t.Run("peer request is not forwarded", func(t *testing.T) {
+		path := pathOwnedBy(instances[1].server.URL)
+		rs, err := http.Get(self.server.URL + internal_peers.PathPrefix + path) //nolint:noctx
+		require.NoError(t, err)
+		rs.Body.Close()
+		require.Equal(t, http.StatusOK, rs.StatusCode)
+		require.Equal(t, int64(1), atomic.LoadInt64(&self.peerRequests))
+		require.Equal(t, int64(1), atomic.LoadInt64(&instances[1].peerRequests))
+	})
+}
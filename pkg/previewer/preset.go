@@ -0,0 +1,120 @@
+package previewer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const presetPathPrefix = "/preset/"
+
+var (
+	ErrInvalidPreset         = errors.New("invalid preset")
+	ErrUnknownPreset         = errors.New("unknown preset")
+	ErrRawDimensionsDisabled = errors.New("raw dimensions are disabled, use presets")
+)
+
+// Preset describes preview: its size, JPEG quality and crop. Zero quality is a default quality of encoder,
+// empty crop is center. Previews of raw dimensions are previews of unnamed presets.
+type Preset struct {
+	Width   int
+	Height  int
+	Quality int
+	Crop    Crop
+}
+
+// fingerprint returns short hash of preset definition. It is a part of cache key, so previews made
+// by the old definition are not served after the definition is changed.
+func (pr Preset) fingerprint() string {
+	crop := pr.Crop
+	if crop == "" {
+		crop = CropCenter
+	}
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%d/%d/%d/%s", pr.Width, pr.Height, pr.Quality, crop)))
+
+	return hex.EncodeToString(hash[:4])
+}
+
+// check returns error if preset can not be rendered within limits.
+func (pr Preset) check(limits Limits) error {
+	if err := limits.check(pr.Width, pr.Height); err != nil {
+		return err
+	}
+	if pr.Quality < 0 || pr.Quality > 100 {
+		return errors.New("quality value must be in range [0, 100], 0 = default")
+	}
+	if pr.Crop != "" && pr.Crop != CropCenter && pr.Crop != CropSmart {
+		return fmt.Errorf("crop %q is not supported", pr.Crop)
+	}
+
+	return nil
+}
+
+// checkPresets returns error if any preset is invalid or presets only mode has no presets.
+func (p *Previewer) checkPresets() error {
+	if p.presetsOnly && len(p.presets) == 0 {
+		return fmt.Errorf("%s: %w", ErrInvalidPreset, errors.New("no presets are given for presets only mode"))
+	}
+	for name, preset := range p.presets {
+		if name == "" || strings.ContainsAny(name, "/?#@") {
+			return fmt.Errorf("%s: %w", ErrInvalidPreset, fmt.Errorf("name %q must be a path segment", name))
+		}
+		if err := preset.check(p.limits); err != nil {
+			return fmt.Errorf("%s %s: %w", ErrInvalidPreset, name, err)
+		}
+	}
+
+	return nil
+}
+
+// presetTransform returns transform of source image by named preset.
+func (p *Previewer) presetTransform(name string, source string) (transform, error) {
+	preset, ok := p.presets[name]
+	if !ok {
+		return transform{}, fmt.Errorf("%s: %w", ErrUnknownPreset, fmt.Errorf("%q", name))
+	}
+
+	return transform{
+		mode:    modeFill,
+		preset:  name,
+		width:   preset.Width,
+		height:  preset.Height,
+		quality: preset.Quality,
+		crop:    preset.Crop,
+		source:  source,
+	}, nil
+}
+
+// parsePreset returns transform from path like /preset/thumb/{URL} or /preset/thumb@{fingerprint}/{URL},
+// query of request is a query of source URL.
+func (p *Previewer) parsePreset(path string, rawQuery string) (transform, error) {
+	parts := strings.SplitN(strings.TrimPrefix(path, presetPathPrefix), "/", 2)
+	if len(parts) < 2 {
+		return transform{}, fmt.Errorf("%s: %w", ErrCanNotParsePath, errors.New("missing expected elements in URL"))
+	}
+
+	source, err := getURL(parts[1])
+	if err != nil {
+		return transform{}, fmt.Errorf("%s: %w", ErrCanNotParsePath, err)
+	}
+	if rawQuery != "" {
+		source += "?" + rawQuery
+	}
+
+	// cache key of preset preview holds fingerprint of preset: /preset/thumb@{fingerprint}/{URL}
+	name, fingerprint := parts[0], ""
+	if i := strings.IndexByte(name, '@'); i >= 0 {
+		name, fingerprint = name[:i], name[i+1:]
+	}
+	t, err := p.presetTransform(name, source)
+	if err != nil {
+		return transform{}, err
+	}
+	if fingerprint != "" && fingerprint != t.options().fingerprint() {
+		return transform{}, fmt.Errorf("%s: %w", ErrCanNotParsePath, fmt.Errorf("preset %q has another definition", name))
+	}
+
+	return t, nil
+}
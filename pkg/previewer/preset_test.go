@@ -0,0 +1,182 @@
+package previewer
+
+import (
+	"bytes"
+	"fmt"
+	"image/jpeg"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testPresets = map[string]Preset{
+	"thumb": {Width: 150, Height: 150, Quality: 80, Crop: CropSmart},
+	"card":  {Width: 400, Height: 300},
+}
+
+func TestCheckPresets(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		options []Option
+		err     string
+	}{
+		{"valid", []Option{WithPresets(testPresets), WithPresetsOnly()}, ""},
+		{"too wide", []Option{WithPresets(map[string]Preset{"wide": {Width: 3000, Height: 100}})},
+			"invalid preset wide: width value must be in range [50, 2000]"},
+		{"too low", []Option{WithPresets(map[string]Preset{"low": {Width: 100, Height: 10}})},
+			"invalid preset low: height value must be in range [50, 2000]"},
+		{"default quality", []Option{WithPresets(map[string]Preset{"card": {Width: 100, Height: 100, Quality: 0}})}, ""},
+		{"quality", []Option{WithPresets(map[string]Preset{"hq": {Width: 100, Height: 100, Quality: 101}})},
+			"invalid preset hq: quality value must be in range [0, 100], 0 = default"},
+		{"crop", []Option{WithPresets(map[string]Preset{"top": {Width: 100, Height: 100, Crop: "top"}})},
+			`invalid preset top: crop "top" is not supported`},
+		{"name", []Option{WithPresets(map[string]Preset{"a/b": {Width: 100, Height: 100}})},
+			`invalid preset: name "a/b" must be a path segment`},
+		{"fingerprint in name", []Option{WithPresets(map[string]Preset{"a@1": {Width: 100, Height: 100}})},
+			`invalid preset: name "a@1" must be a path segment`},
+		{"presets only", []Option{WithPresetsOnly()},
+			"invalid preset: no presets are given for presets only mode"},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(append([]Option{
+				WithLimits(Limits{MinWidth: 50, MinHeight: 50, MaxWidth: 2000, MaxHeight: 2000}),
+			}, tc.options...)...)
+			if tc.err == "" {
+				require.NoError(t, err)
+
+				return
+			}
+			require.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestPresets(t *testing.T) {
+	p := newTestPreviewer(t, WithPresets(testPresets))
+	defer p.cache.Clear()
+	log.SetOutput(ioutil.Discard)
+
+	requests := 0
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		imageServerHandleFunc(w, r)
+	}))
+	defer imageServer.Close()
+
+	previewServer := httptest.NewServer(p)
+	defer previewServer.Close()
+
+	get := func(target string) (int, []byte) {
+		rs, err := http.Get(previewServer.URL + target) //nolint:noctx
+		require.NoError(t, err)
+		defer rs.Body.Close()
+		body, err := ioutil.ReadAll(rs.Body)
+		require.NoError(t, err)
+
+		return rs.StatusCode, body
+	}
+
+	origin := strings.TrimPrefix(imageServer.URL, "http://")
+	status, fromPath := get(fmt.Sprintf("/preset/thumb/%s/images/source.jpg", origin))
+	require.Equal(t, http.StatusOK, status, string(fromPath))
+	config, err := jpeg.DecodeConfig(bytes.NewReader(fromPath))
+	require.NoError(t, err)
+	require.Equal(t, 150, config.Width)
+	require.Equal(t, 150, config.Height)
+
+	status, fromQuery := get("/preview?" + url.Values{
+		"url":    {imageServer.URL + "/images/source.jpg"},
+		"preset": {"thumb"},
+	}.Encode())
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, fromPath, fromQuery)
+	require.Equal(t, 1, requests) // the second preview is taken from cache
+
+	key := fmt.Sprintf("/preset/thumb@%s/%s/images/source.jpg", testPresets["thumb"].fingerprint(), origin)
+	_, _, ok, err := p.cache.GetFile(key)
+	require.NoError(t, err)
+	require.True(t, ok)
+	status, fromKey := get(key) // key is a path of preview, so peers request it as is
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, fromPath, fromKey)
+	status, _ = get(fmt.Sprintf("/preset/thumb@00000000/%s/images/source.jpg", origin))
+	require.Equal(t, http.StatusBadRequest, status)
+
+	// raw dimensions of preset make another preview, quality and crop of preset are not applied to them
+	status, raw := get(fmt.Sprintf("/fill/150/150/%s/images/source.jpg", origin))
+	require.Equal(t, http.StatusOK, status)
+	require.NotEqual(t, fromPath, raw)
+
+	status, _ = get(fmt.Sprintf("/preset/huge/%s/images/source.jpg", origin))
+	require.Equal(t, http.StatusBadRequest, status)
+	status, _ = get("/preset/thumb")
+	require.Equal(t, http.StatusBadRequest, status)
+
+	preview, err := p.TransformPreset(fromPath, "card")
+	require.NoError(t, err)
+	config, err = jpeg.DecodeConfig(bytes.NewReader(preview))
+	require.NoError(t, err)
+	require.Equal(t, 400, config.Width)
+	require.Equal(t, 300, config.Height)
+}
+
+func TestPresetKey(t *testing.T) {
+	p := newTestPreviewer(t, WithPresets(testPresets))
+	changed := newTestPreviewer(t, WithPresets(map[string]Preset{"thumb": {Width: 150, Height: 150, Quality: 90}}))
+
+	path := "/preset/thumb/example.com/a.jpg"
+	before, err := p.parsePathStyle(path, "v=3")
+	require.NoError(t, err)
+	after, err := changed.parsePathStyle(path, "v=3")
+	require.NoError(t, err)
+	require.NotEqual(t, before.key(), after.key()) // previews of the old definition are not served
+
+	require.Equal(t, Preset{Width: 150, Height: 150}.fingerprint(),
+		Preset{Width: 150, Height: 150, Crop: CropCenter}.fingerprint())
+
+	_, err = changed.parsePathStyle(before.key(), "")
+	require.EqualError(t, err, `can not parse path: preset "thumb" has another definition`)
+}
+
+func TestPresetsOnly(t *testing.T) {
+	p := newTestPreviewer(t, WithPresets(testPresets), WithPresetsOnly())
+	defer p.cache.Clear()
+	log.SetOutput(ioutil.Discard)
+
+	requests := 0
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		imageServerHandleFunc(w, r)
+	}))
+	defer imageServer.Close()
+
+	previewServer := httptest.NewServer(p)
+	defer previewServer.Close()
+
+	origin := strings.TrimPrefix(imageServer.URL, "http://")
+	for _, tc := range []struct {
+		target string
+		status int
+	}{
+		{fmt.Sprintf("/fill/150/150/%s/images/source.jpg", origin), http.StatusForbidden},
+		{"/preview?" + url.Values{"url": {origin + "/images/source.jpg"}, "w": {"150"}, "h": {"150"}}.Encode(),
+			http.StatusForbidden},
+		{fmt.Sprintf("/preset/card/%s/images/source.jpg", origin), http.StatusOK},
+	} {
+		rs, err := http.Get(previewServer.URL + tc.target) //nolint:noctx
+		require.NoError(t, err)
+		rs.Body.Close()
+		require.Equal(t, tc.status, rs.StatusCode, tc.target)
+	}
+	require.Equal(t, 1, requests) // source image is loaded for presets only
+
+	results := p.warmUp([]string{fmt.Sprintf("/fill/150/150/%s/images/source.jpg", origin)}, 1)
+	require.Equal(t, http.StatusForbidden, results[0].Status)
+}
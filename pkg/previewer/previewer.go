@@ -0,0 +1,444 @@
+// Package previewer makes previews of JPEG images: it loads source images, cuts them to requested size
+// and caches both source images and previews. Previewer may be embedded into other services or served by HTTP.
+package previewer
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	internal_cache "github.com/sinuspower/image-previewer/internal/cache"
+	internal_peers "github.com/sinuspower/image-previewer/internal/peers"
+)
+
+// Previewer renders previews of source images: it loads source image by Fetcher, transforms it
+// by Processor and keeps both source images and previews in Cache.
+type Previewer struct {
+	cache             Cache
+	fetcher           Fetcher
+	processor         Processor
+	limits            Limits
+	forwardHeaders    []string // client request headers sent to origins
+	passHeaders       []string // origin response headers sent to clients
+	cacheControl      string
+	adminToken        string   // admin endpoints are disabled if it is empty
+	signingKeys       []string // previews are served by signed URLs only if it is not empty
+	presets           map[string]Preset
+	presetsOnly       bool // previews of raw dimensions are rejected
+	warmUpConcurrency int
+	peers             *internal_peers.Pool     // nil if peering is disabled
+	watchdog          *internal_cache.Watchdog // nil if free disk space is not watched
+	diskLowWater      uint64
+	diskCritical      uint64
+	logger            *log.Logger
+	router            *http.ServeMux
+}
+
+var (
+	ErrWritingResponse = errors.New("error writing response to client")
+	ErrCanNotLoadImage = errors.New("can not load image from server")
+	ErrCanNotCutImage  = errors.New("can not cut image")
+	ErrInvalidLimits   = errors.New("invalid preview limits")
+	ErrCanNotWatchDisk = errors.New("can not watch free disk space")
+)
+
+// New creates previewer, without options it keeps images in memory and loads them by HTTP.
+func New(options ...Option) (*Previewer, error) {
+	p := &Previewer{
+		limits:            DefaultLimits,
+		forwardHeaders:    defaultForwardHeaders,
+		cacheControl:      defaultCacheControl,
+		warmUpConcurrency: defaultWarmUpConcurrency,
+		logger:            log.Default(),
+	}
+	for _, option := range options {
+		option(p)
+	}
+
+	if p.limits.MinWidth < 1 || p.limits.MinHeight < 1 ||
+		p.limits.MinWidth > p.limits.MaxWidth || p.limits.MinHeight > p.limits.MaxHeight {
+		return nil, fmt.Errorf("%s: %w", ErrInvalidLimits,
+			errors.New("minimal dimensions must be positive and not greater than maximal ones"))
+	}
+	if err := p.checkPresets(); err != nil {
+		return nil, err
+	}
+	if p.warmUpConcurrency < 1 { // warm-up would wait for workers forever
+		return nil, fmt.Errorf("%s: %w", ErrInvalidWarmUpSettings, errors.New("concurrency must be positive"))
+	}
+	if p.cache == nil {
+		p.cache = internal_cache.NewMemoryStorage(defaultCacheSize)
+	}
+	if p.fetcher == nil {
+		p.fetcher = NewHTTPFetcher(nil)
+	}
+	if p.processor == nil {
+		p.processor = NewCutter()
+	}
+	if p.diskLowWater > 0 || p.diskCritical > 0 {
+		watchdog, err := internal_cache.NewWatchdog(p.cache, internal_cache.WatchdogConfig{
+			LowWater: p.diskLowWater,
+			Critical: p.diskCritical,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ErrCanNotWatchDisk, err)
+		}
+		p.watchdog = watchdog
+	}
+	p.router = p.newRouter()
+
+	return p, nil
+}
+
+// newRouter registers all routes: preview modes, their peer counterparts, admin and health endpoints.
+// If URLs must be signed, previews are served with signature segment only, i.e. /{signature}/fill/300/200/{URL}.
+func (p *Previewer) newRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+	if len(p.signingKeys) > 0 {
+		mux.HandleFunc("/", p.signedHandler)
+		mux.HandleFunc("/fill/", p.unsignedHandler)
+		mux.HandleFunc(presetPathPrefix, p.unsignedHandler)
+		mux.HandleFunc(queryStylePath, p.unsignedHandler)
+		mux.HandleFunc(internal_peers.PathPrefix+"/", p.peerHandler)
+	} else {
+		mux.HandleFunc("/fill/", p.pathHandler)
+		mux.HandleFunc(presetPathPrefix, p.pathHandler)
+		mux.HandleFunc(queryStylePath, p.previewHandler)
+		mux.HandleFunc(internal_peers.PathPrefix+"/fill/", p.peerHandler)
+		mux.HandleFunc(internal_peers.PathPrefix+presetPathPrefix, p.peerHandler)
+	}
+	mux.HandleFunc("/admin/purge", p.adminPurgeHandler)
+	mux.HandleFunc("/admin/cache", p.adminCacheHandler)
+	mux.HandleFunc("/admin/warmup", p.adminWarmUpHandler)
+	mux.HandleFunc("/health", p.healthHandler)
+
+	return mux
+}
+
+// ServeHTTP serves previews like /fill/300/200/{URL}, /preset/thumb/{URL} or /preview?url={URL}&w=300&h=200 as well as admin, health and peer endpoints.
+func (p *Previewer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.router.ServeHTTP(w, r)
+}
+
+// Fetch returns source image from cache or loads it and puts it into cache.
+// Header holds headers of client request allowed to be sent to origin.
+func (p *Previewer) Fetch(source string, header http.Header) ([]byte, http.Header, error) {
+	image, meta, ok, err := p.cache.GetFile(source)
+	if err != nil {
+		p.logger.Println("[WARN] can not get source image from cache:", err)
+	}
+	if ok {
+		p.logger.Println("[INFO] get source image from cache")
+
+		return image, metadataHeader(meta), nil
+	}
+
+	p.logger.Println("[INFO] send request to", source)
+	image, rsHeader, err := p.fetcher.Fetch(source, header)
+	if err != nil {
+		return nil, nil, err
+	}
+	p.logger.Println("[INFO] get response from", source)
+
+	err = p.cache.PutFile(source, image, internal_cache.Metadata{
+		ContentType:  rsHeader.Get("Content-Type"),
+		ETag:         rsHeader.Get("ETag"),
+		LastModified: rsHeader.Get("Last-Modified"),
+		Headers:      p.passedHeaders(rsHeader),
+	})
+	if err != nil {
+		p.logger.Println("[WARN] can not put source image into cache:", err)
+	} else {
+		p.logger.Println("[INFO] put source image into cache")
+	}
+
+	return image, rsHeader, nil
+}
+
+// Transform makes preview of given size from source image, dimensions are checked against limits.
+func (p *Previewer) Transform(source []byte, width int, height int) ([]byte, error) {
+	if err := p.limits.check(width, height); err != nil {
+		return nil, err
+	}
+
+	return p.processor.Process(source, Preset{Width: width, Height: height})
+}
+
+// TransformPreset makes preview from source image by named preset.
+func (p *Previewer) TransformPreset(source []byte, name string) ([]byte, error) {
+	preset, ok := p.presets[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", ErrUnknownPreset, fmt.Errorf("%q", name))
+	}
+
+	return p.processor.Process(source, preset)
+}
+
+// RunWatchdog checks free space on cache volume until stop is closed, if watchdog is configured.
+func (p *Previewer) RunWatchdog(stop <-chan struct{}) {
+	if p.watchdog != nil {
+		p.runWatchdog(stop)
+	}
+}
+
+// pathHandler serves previews requested by path: GET /fill/300/200/{URL} or GET /preset/thumb/{URL},
+// query belongs to source URL.
+func (p *Previewer) pathHandler(w http.ResponseWriter, r *http.Request) {
+	p.logger.Printf("[INFO] get request from %s; path: %s", r.RemoteAddr, r.URL.RequestURI())
+
+	t, err := p.parsePathStyle(r.URL.Path, r.URL.RawQuery)
+	p.servePreview(w, r, t, err)
+}
+
+// previewHandler serves previews requested by query: GET /preview?url={URL}&w=300&h=200&mode=fill
+// or GET /preview?url={URL}&preset=thumb.
+func (p *Previewer) previewHandler(w http.ResponseWriter, r *http.Request) {
+	p.logger.Printf("[INFO] get request from %s; query: %s", r.RemoteAddr, r.URL.RawQuery)
+
+	t, err := p.parseQueryStyle(r.URL.Query())
+	p.servePreview(w, r, t, err)
+}
+
+// servePreview sends preview of transform or answers 304 if client copy is not modified.
+// Err is an error of parsing transform from request.
+func (p *Previewer) servePreview(w http.ResponseWriter, r *http.Request, t transform, err error) {
+	fromHost := r.RemoteAddr
+	if err != nil {
+		p.sendResponse(w, parseStatus(err), nil, fromHost, nil, err)
+
+		return
+	}
+	rqHeader := forwardHeader(r, p.forwardHeaders) // headers allowed to be sent to origin
+
+	conditional := isConditional(r)
+	if conditional {
+		if header, ok := p.cachedHeader(t.key()); ok && notModified(r.Header, header) {
+			p.logger.Println("[INFO] preview is not modified")
+			p.setCacheControl(header)
+			p.sendResponse(w, http.StatusNotModified, header, fromHost, nil, nil)
+
+			return
+		}
+	}
+	preview := p.makePreview(t, rqHeader, true)
+	if preview.err == nil {
+		preview.header = p.previewHeader(preview.header)
+		p.setCacheControl(preview.header)
+		if conditional && notModified(r.Header, preview.header) {
+			preview.status, preview.image = http.StatusNotModified, nil
+		}
+	}
+	p.sendResponse(w, preview.status, preview.header, fromHost, preview.image, preview.err)
+}
+
+// ownHeaders describe preview itself, they are taken from metadata rather than from origin response.
+var ownHeaders = []string{"Content-Type", "ETag", "Last-Modified"}
+
+// previewHeader keeps headers describing preview and origin headers allowed to pass to client.
+// Other origin headers, i.e. Content-Length or Set-Cookie, do not describe re-encoded preview.
+func (p *Previewer) previewHeader(header http.Header) http.Header {
+	filtered := http.Header{}
+	for _, names := range [][]string{p.passHeaders, ownHeaders} {
+		for _, name := range names {
+			if values := header.Values(name); len(values) > 0 {
+				filtered[http.CanonicalHeaderKey(name)] = values
+			}
+		}
+	}
+
+	return filtered
+}
+
+// passedHeaders returns origin headers allowed to pass to client, they are kept in metadata of cached image.
+func (p *Previewer) passedHeaders(header http.Header) map[string][]string {
+	var passed map[string][]string
+	for _, name := range p.passHeaders {
+		if values := header.Values(name); len(values) > 0 {
+			if passed == nil {
+				passed = make(map[string][]string)
+			}
+			passed[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+
+	return passed
+}
+
+// setCacheControl adds configured Cache-Control header to response for client.
+func (p *Previewer) setCacheControl(header http.Header) {
+	if cacheControl := p.cacheControl; cacheControl != "" {
+		header.Set("Cache-Control", cacheControl)
+	}
+}
+
+// peerHandler serves previews owned by this instance to other peers, requests are never forwarded further.
+func (p *Previewer) peerHandler(w http.ResponseWriter, r *http.Request) {
+	fromHost := r.RemoteAddr
+	path := strings.TrimPrefix(r.URL.Path, internal_peers.PathPrefix)
+	rqHeader := forwardHeader(r, p.forwardHeaders)
+
+	p.logger.Printf("[INFO] get peer request from %s; path: %s", fromHost, path)
+
+	if len(p.signingKeys) > 0 { // peers sign their requests too, so peer endpoint does not bypass signatures
+		var ok bool
+		if path, ok = p.verify(w, r, path); !ok {
+			return
+		}
+	}
+	t, err := p.parsePathStyle(path, r.URL.RawQuery)
+	if err != nil {
+		p.sendResponse(w, parseStatus(err), nil, fromHost, nil, err)
+
+		return
+	}
+	preview := p.makePreview(t, rqHeader, false)
+	if preview.err == nil {
+		preview.header = p.previewHeader(preview.header)
+	}
+	p.sendResponse(w, preview.status, preview.header, fromHost, preview.image, preview.err)
+}
+
+// preview is a result of preview pipeline.
+type preview struct {
+	image  []byte
+	header http.Header // headers for response
+	status int
+	cached bool
+	err    error
+}
+
+// makePreview returns preview from cache or loads source image, cuts it and puts preview into cache.
+// If forward is set and preview is owned by another peer, it is requested from the owner first.
+func (p *Previewer) makePreview(t transform, rqHeader http.Header, forward bool) preview {
+	path := t.key()
+
+	// make response from cache if requested image is in cache
+	image, meta, ok, err := p.cache.GetFile(path)
+	if err != nil {
+		p.logger.Println("[WARN] can not get preview from cache:", err)
+	}
+	if ok {
+		p.logger.Println("[INFO] get preview from cache")
+
+		return preview{image: image, header: metadataHeader(meta), status: 200, cached: true}
+	}
+
+	if forward && p.peers != nil {
+		if owner, remote := p.peers.Owner(path); remote {
+			image, rsHeader, err := p.peers.Fetch(owner, p.peerPath(path), rqHeader)
+			if err == nil {
+				p.logger.Println("[INFO] get preview from peer", owner)
+
+				return preview{image: image, header: rsHeader, status: 200}
+			}
+			p.logger.Println("[WARN] can not get preview from peer, render it locally:", err)
+		}
+	}
+
+	image, rsHeader, err := p.Fetch(t.source, rqHeader)
+	if err != nil {
+		return preview{status: 500, err: fmt.Errorf("%s: %w", ErrCanNotLoadImage, err)}
+	}
+
+	image, err = p.processor.Process(image, t.options())
+	if err != nil {
+		return preview{status: 500, err: fmt.Errorf("%s: %w", ErrCanNotCutImage, err)}
+	}
+
+	// put resized image into cache
+	meta = internal_cache.Metadata{
+		ContentType:  "image/jpeg",
+		ETag:         previewETag(image),
+		LastModified: rsHeader.Get("Last-Modified"),
+		Created:      time.Now(),
+		Width:        t.width,
+		Height:       t.height,
+		Headers:      p.passedHeaders(rsHeader),
+	}
+	err = p.putPreview(t.source, path, image, meta)
+	if err != nil {
+		p.logger.Println("[WARN] can not put preview into cache:", err)
+	} else {
+		p.logger.Println("[INFO] put preview into cache")
+	}
+
+	// the same headers are replayed on cache hits
+	for key, values := range metadataHeader(meta) {
+		rsHeader[key] = values
+	}
+
+	return preview{image: image, header: rsHeader, status: 200}
+}
+
+// peerPath returns path of preview for request to peer, it is signed if URLs must be signed.
+func (p *Previewer) peerPath(path string) string {
+	if len(p.signingKeys) > 0 {
+		return SignURL(p.signingKeys[0], path)
+	}
+
+	return path
+}
+
+// putPreview remembers source of preview if cache is able to purge previews by source.
+func (p *Previewer) putPreview(source string, path string, image []byte, meta internal_cache.Metadata) error {
+	if purger, ok := p.cache.(internal_cache.Purger); ok {
+		return purger.PutDerivedFile(source, path, image, meta)
+	}
+
+	return p.cache.PutFile(path, image, meta)
+}
+
+// metadataHeader returns response headers described by metadata of cached image and origin headers
+// passed to client. Images without upstream Last-Modified are considered modified when they are stored.
+func metadataHeader(meta internal_cache.Metadata) http.Header {
+	header := http.Header{}
+	for key, values := range meta.Headers {
+		header[key] = append([]string(nil), values...) // stored metadata is shared by memory cache
+	}
+	if meta.ContentType != "" {
+		header.Set("Content-Type", meta.ContentType)
+	}
+	if meta.ETag != "" {
+		header.Set("ETag", meta.ETag)
+	}
+	if meta.LastModified != "" {
+		header.Set("Last-Modified", meta.LastModified)
+	} else if !meta.Created.IsZero() {
+		header.Set("Last-Modified", meta.Created.UTC().Format(http.TimeFormat))
+	}
+
+	return header
+}
+
+// sendResponse sends data with given headers or error message, Content-Length is always set by data.
+func (p *Previewer) sendResponse(w http.ResponseWriter, status int, header http.Header, toHost string, data []byte, err error) {
+	if err != nil {
+		p.logger.Println("[ERROR]", err)
+		data = []byte(err.Error() + "\n")
+		header = http.Header{"Content-Type": {"text/plain; charset=utf-8"}}
+	}
+
+	// copy headers
+	for key, values := range header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	if status != http.StatusNotModified {
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	}
+	w.WriteHeader(status)
+
+	written, err := w.Write(data)
+	if err != nil {
+		p.logger.Println("[ERROR]", fmt.Errorf("%s: %w", ErrWritingResponse, err))
+
+		return
+	}
+
+	p.logger.Printf("[INFO] send response to %s, %d bytes, status %d", toHost, written, status)
+}
@@ -0,0 +1,321 @@
+package previewer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	internal_cache "github.com/sinuspower/image-previewer/internal/cache"
+	"github.com/stretchr/testify/require"
+)
+
+var imageServerHandleFunc = func(w http.ResponseWriter, r *http.Request) {
+	header := r.Header.Clone()
+	// copy headers
+	for key, values := range header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	f, err := os.Open("../../test/testdata/_gopher_original_1024x504.jpg")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	bytes, err := ioutil.ReadAll(f)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	_, err = w.Write(bytes)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestGetPreviews(t *testing.T) {
+	p := newTestPreviewer(t)
+	log.SetOutput(ioutil.Discard)
+
+	imageServer := httptest.NewServer(http.HandlerFunc(imageServerHandleFunc))
+	defer imageServer.Close()
+
+	previewServer := httptest.NewServer(http.HandlerFunc(p.pathHandler))
+	defer previewServer.Close()
+
+	var testCases = []struct { //nolint:go-lint
+		name        string
+		urlTemplate string
+		filepath    string
+	}{
+		{"50x50", "%s/fill/50/50/%s/images/source.jpg", "../../test/testdata/gopher_50x50.jpg"},
+		{"200x700", "%s/fill/200/700/%s/images/source.jpg", "../../test/testdata/gopher_200x700.jpg"},
+		{"256x126", "%s/fill/256/126/%s/images/source.jpg", "../../test/testdata/gopher_256x126.jpg"},
+		{"333x666", "%s/fill/333/666/%s/images/source.jpg", "../../test/testdata/gopher_333x666.jpg"},
+		{"500x500", "%s/fill/500/500/%s/images/source.jpg", "../../test/testdata/gopher_500x500.jpg"},
+		{"1024x252", "%s/fill/1024/252/%s/images/source.jpg", "../../test/testdata/gopher_1024x252.jpg"},
+		{"1024x504", "%s/fill/1024/504/%s/images/source.jpg", "../../test/testdata/gopher_1024x504.jpg"},
+		{"2000x1000", "%s/fill/2000/1000/%s/images/source.jpg", "../../test/testdata/gopher_2000x1000.jpg"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			url := fmt.Sprintf(tc.urlTemplate, previewServer.URL, imageServer.URL) //nolint:go-lint
+			testGetPreview(t, p, url, tc.filepath)                                 //nolint:go-lint
+		})
+	}
+}
+
+func TestProxyHeaders(t *testing.T) {
+	p := newTestPreviewer(t,
+		WithForwardHeaders([]string{"Header-One", "Header-Two"}),
+		WithPassHeaders([]string{"Header-One", "Header-Two"}))
+	log.SetOutput(ioutil.Discard)
+
+	imageServer := httptest.NewServer(http.HandlerFunc(imageServerHandleFunc))
+	defer imageServer.Close()
+
+	previewServer := httptest.NewServer(http.HandlerFunc(p.pathHandler))
+	defer previewServer.Close()
+
+	url := fmt.Sprintf("%s/fill/50/50/%s/images/source.jpg", previewServer.URL, imageServer.URL)
+	rq, err := http.NewRequestWithContext(context.Background(), "GET", url, nil)
+	require.NoError(t, err)
+
+	rq.Header.Add("Header-One", "test-header-one")
+	rq.Header.Add("Header-Two", "test-header-two")
+	rq.Header.Add("Header-Three", "test-header-three")
+
+	client := new(http.Client)
+	rs, err := client.Do(rq)
+	require.NoError(t, err)
+	defer rs.Body.Close()
+	require.Equal(t, 200, rs.StatusCode)
+
+	require.Equal(t, "test-header-one", rs.Header["Header-One"][0])
+	require.Equal(t, "test-header-two", rs.Header["Header-Two"][0])
+	require.Empty(t, rs.Header.Get("Header-Three")) // not allowed to pass
+
+	body, err := ioutil.ReadAll(rs.Body)
+	require.NoError(t, err)
+	require.Equal(t, "image/jpeg", rs.Header.Get("Content-Type"))
+	require.Equal(t, strconv.Itoa(len(body)), rs.Header.Get("Content-Length"))
+
+	t.Run("cache hits", func(t *testing.T) {
+		origin := strings.TrimPrefix(imageServer.URL, "http://")
+		for _, target := range []string{
+			fmt.Sprintf("/fill/50/50/%s/images/source.jpg", origin), // preview is cached
+			fmt.Sprintf("/fill/60/60/%s/images/source.jpg", origin), // source image is cached
+		} {
+			rs, err := http.Get(previewServer.URL + target) //nolint:noctx
+			require.NoError(t, err)
+			rs.Body.Close()
+			require.Equal(t, 200, rs.StatusCode)
+
+			// origin headers of the first request are replayed without request to origin
+			require.Equal(t, "test-header-one", rs.Header.Get("Header-One"), target)
+			require.Equal(t, "test-header-two", rs.Header.Get("Header-Two"), target)
+			require.Empty(t, rs.Header.Get("Header-Three"))
+		}
+	})
+
+	err = p.cache.Clear()
+	require.NoError(t, err)
+}
+
+func TestWithLogger(t *testing.T) {
+	global := &bytes.Buffer{}
+	log.SetOutput(global)
+	defer log.SetOutput(ioutil.Discard)
+
+	own := &bytes.Buffer{}
+	p := newTestPreviewer(t, WithLogger(log.New(own, "", 0)))
+	defer p.cache.Clear()
+
+	imageServer := httptest.NewServer(http.HandlerFunc(imageServerHandleFunc))
+	defer imageServer.Close()
+
+	previewServer := httptest.NewServer(p)
+	defer previewServer.Close()
+
+	origin := strings.TrimPrefix(imageServer.URL, "http://")
+	rs, err := http.Get(fmt.Sprintf("%s/fill/50/50/%s/images/source.jpg", previewServer.URL, origin)) //nolint:noctx
+	require.NoError(t, err)
+	rs.Body.Close()
+	require.Equal(t, http.StatusOK, rs.StatusCode)
+
+	require.Contains(t, own.String(), "[INFO] get request from")
+	require.Contains(t, own.String(), "[INFO] put preview into cache")
+	require.NotContains(t, global.String(), "[INFO]") // standard logger is left to the caller
+
+	quiet, err := New(WithLogger(nil))
+	require.NoError(t, err)
+	rw := httptest.NewRecorder()
+	quiet.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/fill/wide/1/example.com/a.jpg", nil))
+	require.Equal(t, http.StatusBadRequest, rw.Code)
+	require.NotContains(t, global.String(), "[ERROR]")
+}
+
+// newTestPreviewer creates previewer with file cache in "cache" directory, previews are limited to [50, 2000].
+func newTestPreviewer(t *testing.T, options ...Option) *Previewer {
+	return newTestPreviewerWithCache(t, "cache", options...)
+}
+
+func newTestPreviewerWithCache(t *testing.T, cachePath string, options ...Option) *Previewer {
+	cache, err := internal_cache.NewFileCache(5, cachePath)
+	require.NoError(t, err)
+
+	p, err := New(append([]Option{
+		WithCache(cache),
+		WithLimits(Limits{MinWidth: 50, MinHeight: 50, MaxWidth: 2000, MaxHeight: 2000}),
+	}, options...)...)
+	require.NoError(t, err)
+
+	return p
+}
+
+func testGetPreview(t *testing.T, p *Previewer, url string, filepath string) {
+	rs, err := http.Get(url) //nolint:go-lint
+	require.NoError(t, err)
+	defer rs.Body.Close()
+	require.Equal(t, http.StatusOK, rs.StatusCode)
+
+	f, err := os.Open(filepath)
+	require.NoError(t, err)
+
+	expBytes, err := ioutil.ReadAll(f)
+	require.NoError(t, err)
+
+	actBytes, err := ioutil.ReadAll(rs.Body)
+	require.NoError(t, err)
+
+	require.Equal(t, expBytes, actBytes)
+
+	err = p.cache.Clear()
+	require.NoError(t, err)
+}
+
+func TestCachedHeaders(t *testing.T) {
+	p := newTestPreviewer(t)
+	defer p.cache.Clear()
+	log.SetOutput(ioutil.Discard)
+
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Thu, 01 Oct 2020 12:00:00 GMT")
+		imageServerHandleFunc(w, r)
+	}))
+	defer imageServer.Close()
+
+	previewServer := httptest.NewServer(http.HandlerFunc(p.pathHandler))
+	defer previewServer.Close()
+
+	url := fmt.Sprintf("%s/fill/50/50/%s/images/source.jpg", previewServer.URL, imageServer.URL)
+	headers := []http.Header{}
+	for i := 0; i < 2; i++ { // miss and hit
+		rs, err := http.Get(url) //nolint:noctx
+		require.NoError(t, err)
+		rs.Body.Close()
+		require.Equal(t, http.StatusOK, rs.StatusCode)
+		headers = append(headers, rs.Header)
+	}
+
+	for _, header := range headers {
+		require.Equal(t, "image/jpeg", header.Get("Content-Type"))
+		require.Equal(t, headers[0].Get("ETag"), header.Get("ETag")) // ETag of preview, not of source
+		require.NotEqual(t, `"v1"`, header.Get("ETag"))
+		require.Equal(t, "Thu, 01 Oct 2020 12:00:00 GMT", header.Get("Last-Modified"))
+		require.Equal(t, "public, max-age=86400", header.Get("Cache-Control"))
+	}
+
+	_, meta, ok, err := p.cache.GetFile(fmt.Sprintf("/fill/50/50/%s/images/source.jpg", strings.TrimPrefix(imageServer.URL, "http://")))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 50, meta.Width)
+	require.Equal(t, 50, meta.Height)
+	require.False(t, meta.Created.IsZero())
+}
+
+func TestUnsafeOriginHeaders(t *testing.T) {
+	p := newTestPreviewer(t)
+	defer p.cache.Clear()
+	log.SetOutput(ioutil.Discard)
+
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=origin")
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("X-Origin", "origin")
+		imageServerHandleFunc(w, r)
+	}))
+	defer imageServer.Close()
+
+	previewServer := httptest.NewServer(http.HandlerFunc(p.pathHandler))
+	defer previewServer.Close()
+
+	rs, err := http.Get(fmt.Sprintf("%s/fill/50/50/%s/images/source.jpg", previewServer.URL, imageServer.URL)) //nolint:noctx
+	require.NoError(t, err)
+	defer rs.Body.Close()
+	body, err := ioutil.ReadAll(rs.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rs.StatusCode)
+
+	require.Equal(t, "image/jpeg", rs.Header.Get("Content-Type"))
+	require.Equal(t, strconv.Itoa(len(body)), rs.Header.Get("Content-Length"))
+	require.Empty(t, rs.Header.Get("Set-Cookie"))
+	require.Empty(t, rs.Header.Get("X-Origin"))
+
+	rs, err = http.Get(previewServer.URL + "/fill/50/50/bad.png") //nolint:noctx
+	require.NoError(t, err)
+	defer rs.Body.Close()
+	require.Equal(t, http.StatusBadRequest, rs.StatusCode)
+	require.Equal(t, "text/plain; charset=utf-8", rs.Header.Get("Content-Type"))
+}
+
+func TestNew(t *testing.T) {
+	p := newTestPreviewer(t, WithAdminToken("secret"))
+	defer p.cache.Clear()
+	log.SetOutput(ioutil.Discard)
+
+	// every previewer has its own router, so registering routes twice does not panic
+	servers := []*httptest.Server{
+		httptest.NewServer(p),
+		httptest.NewServer(newTestPreviewer(t, WithAdminToken("secret"))),
+	}
+
+	for _, server := range servers {
+		defer server.Close()
+		for path, status := range map[string]int{
+			"/health":             http.StatusOK,
+			"/fill/50/50/bad.png": http.StatusBadRequest,
+			"/admin/cache":        http.StatusUnauthorized,
+			"/unknown":            http.StatusNotFound,
+		} {
+			rs, err := http.Get(server.URL + path) //nolint:noctx
+			require.NoError(t, err)
+			rs.Body.Close()
+			require.Equal(t, status, rs.StatusCode, path)
+		}
+	}
+
+	t.Run("invalid limits", func(t *testing.T) {
+		_, err := New(WithLimits(Limits{MinWidth: 100, MinHeight: 1, MaxWidth: 50, MaxHeight: 50}))
+		require.EqualError(t, err,
+			"invalid preview limits: minimal dimensions must be positive and not greater than maximal ones")
+	})
+}
@@ -0,0 +1,93 @@
+package previewer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var ErrBadSignature = errors.New("signature is missing or invalid")
+
+// Sign returns signature of preview target like /fill/300/200/{URL} or /preview?url={URL}&w=300&h=200:
+// URL-safe base64 of its HMAC-SHA256.
+func Sign(key string, target string) string {
+	return base64.RawURLEncoding.EncodeToString(signature(key, target))
+}
+
+// SignURL returns target with signature segment in front of it: /{signature}/fill/300/200/{URL}.
+func SignURL(key string, target string) string {
+	return "/" + Sign(key, target) + target
+}
+
+func signature(key string, target string) []byte {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(target))
+
+	return mac.Sum(nil)
+}
+
+// verifySignature checks signature of target with every key in constant time, so old keys work during rotation.
+func verifySignature(keys []string, encoded string, target string) bool {
+	given, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return false
+	}
+
+	valid := false
+	for _, key := range keys {
+		if hmac.Equal(given, signature(key, target)) {
+			valid = true
+		}
+	}
+
+	return valid
+}
+
+// verify splits signature segment from path like /{signature}/fill/300/200/{URL} and checks it with query
+// of request. It sends 403 and returns false if signature does not match.
+func (p *Previewer) verify(w http.ResponseWriter, r *http.Request, path string) (string, bool) {
+	encoded, target := "", ""
+	if parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2); len(parts) == 2 {
+		encoded, target = parts[0], "/"+parts[1]
+	}
+
+	signed := target
+	if r.URL.RawQuery != "" {
+		signed += "?" + r.URL.RawQuery
+	}
+	if target == "" || !verifySignature(p.signingKeys, encoded, signed) {
+		p.sendResponse(w, http.StatusForbidden, nil, r.RemoteAddr, nil, ErrBadSignature)
+
+		return "", false
+	}
+
+	return target, true
+}
+
+// signedHandler serves previews with signature segment: GET /{signature}/fill/300/200/{URL},
+// GET /{signature}/preset/thumb/{URL} or GET /{signature}/preview?url={URL}&w=300&h=200. Nothing is parsed or loaded before signature is checked.
+func (p *Previewer) signedHandler(w http.ResponseWriter, r *http.Request) {
+	target, ok := p.verify(w, r, r.URL.Path)
+	if !ok {
+		return
+	}
+
+	r = r.Clone(r.Context())
+	r.URL.Path, r.URL.RawPath = target, ""
+	switch {
+	case strings.HasPrefix(target, "/fill/"), strings.HasPrefix(target, presetPathPrefix):
+		p.pathHandler(w, r)
+	case target == queryStylePath:
+		p.previewHandler(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// unsignedHandler rejects previews without signature if URLs must be signed.
+func (p *Previewer) unsignedHandler(w http.ResponseWriter, r *http.Request) {
+	p.sendResponse(w, http.StatusForbidden, nil, r.RemoteAddr, nil, ErrBadSignature)
+}
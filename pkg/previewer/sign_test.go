@@ -0,0 +1,85 @@
+package previewer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	internal_peers "github.com/sinuspower/image-previewer/internal/peers"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	newKey = "new-secret-key-0001"
+	oldKey = "old-secret-key-0000"
+)
+
+func TestSign(t *testing.T) {
+	target := "/fill/300/200/example.com/a.jpg?v=3"
+	signature := Sign(newKey, target)
+	require.Len(t, signature, 43) // 32 bytes of SHA-256 in unpadded base64
+	require.Equal(t, "/"+signature+target, SignURL(newKey, target))
+
+	keys := []string{newKey, oldKey}
+	require.True(t, verifySignature(keys, signature, target))
+	require.True(t, verifySignature(keys, Sign(oldKey, target), target))
+	require.False(t, verifySignature(keys, Sign("another-secret-key", target), target))
+	require.False(t, verifySignature(keys, signature, "/fill/3000/200/example.com/a.jpg?v=3"))
+	require.False(t, verifySignature(keys, signature+"=", target))
+	require.False(t, verifySignature(keys, "", target))
+}
+
+func TestSignedURLs(t *testing.T) {
+	p := newTestPreviewer(t, WithSigningKeys(newKey, oldKey))
+	defer p.cache.Clear()
+	log.SetOutput(ioutil.Discard)
+
+	requests := 0
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		imageServerHandleFunc(w, r)
+	}))
+	defer imageServer.Close()
+
+	previewServer := httptest.NewServer(p)
+	defer previewServer.Close()
+
+	origin := strings.TrimPrefix(imageServer.URL, "http://")
+	path := fmt.Sprintf("/fill/50/50/%s/images/source.jpg", origin)
+	query := "/preview?" + url.Values{"url": {imageServer.URL + "/images/source.jpg"}, "w": {"60"}, "h": {"60"}}.Encode()
+	status := func(target string) int {
+		rs, err := http.Get(previewServer.URL + target) //nolint:noctx
+		require.NoError(t, err)
+		rs.Body.Close()
+
+		return rs.StatusCode
+	}
+
+	for _, tc := range []struct {
+		name   string
+		target string
+		status int
+	}{
+		{"unsigned path", path, http.StatusForbidden},
+		{"unsigned query", query, http.StatusForbidden},
+		{"unsigned peer", internal_peers.PathPrefix + path, http.StatusForbidden},
+		{"bad signature", "/" + Sign(newKey, path) + strings.Replace(path, "50/50", "500/500", 1), http.StatusForbidden},
+		{"no signature", "/" + strings.Repeat("A", 43), http.StatusForbidden},
+		{"unknown key", SignURL("another-secret-key", path), http.StatusForbidden},
+		{"health", "/health", http.StatusOK},
+		{"path", SignURL(newKey, path), http.StatusOK},
+		{"old key", SignURL(oldKey, path+"?v=2"), http.StatusOK},
+		{"query", SignURL(newKey, query), http.StatusOK},
+		{"signed peer", internal_peers.PathPrefix + SignURL(newKey, path), http.StatusOK},
+		{"signed bad path", SignURL(newKey, "/fill/50/50/bad.png"), http.StatusBadRequest},
+		{"signed unknown", SignURL(newKey, "/unknown"), http.StatusNotFound},
+	} {
+		require.Equal(t, tc.status, status(tc.target), tc.name)
+	}
+	require.Equal(t, 2, requests) // source image is loaded for signed requests only, v=2 is another source
+}
@@ -0,0 +1,138 @@
+package previewer
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	modeFill       = "fill"
+	queryStylePath = "/preview" // path of query style URLs
+)
+
+var ErrCanNotParseQuery = errors.New("can not parse query")
+
+// transform is a preview requested by client, it is the same for path and query URL styles.
+type transform struct {
+	mode    string
+	preset  string // name of preset, empty for raw dimensions
+	width   int
+	height  int
+	quality int
+	crop    Crop
+	source  string // URL of source image
+}
+
+// key returns canonical cache key of preview. It is a path of path style URL, so it may be sent to peers as is.
+// Previews of presets are keyed by name and fingerprint of preset definition, i.e. /preset/thumb@1a2b3c4d/{URL}.
+func (t transform) key() string {
+	if t.preset != "" {
+		return presetPathPrefix + t.preset + "@" + t.options().fingerprint() + "/" +
+			strings.TrimPrefix(t.source, "http://")
+	}
+
+	return "/" + t.mode + "/" + strconv.Itoa(t.width) + "/" + strconv.Itoa(t.height) + "/" +
+		strings.TrimPrefix(t.source, "http://")
+}
+
+// options returns preset of preview rendering.
+func (t transform) options() Preset {
+	return Preset{Width: t.width, Height: t.height, Quality: t.quality, Crop: t.crop}
+}
+
+// parseTransform returns transform from path like /fill/300/200/{URL}. Query of request is a query of source URL,
+// so origins get their tokens and version stamps, and versions of source image are cached separately.
+func parseTransform(path string, rawQuery string, limits Limits) (transform, error) {
+	width, height, source, err := parsePath(path, limits)
+	if err != nil {
+		return transform{}, err
+	}
+	if rawQuery != "" {
+		source += "?" + rawQuery
+	}
+
+	return transform{mode: modeFill, width: width, height: height, source: source}, nil
+}
+
+// parseQuery returns transform from query like url={URL}&w=300&h=200&mode=fill, mode is fill by default.
+func parseQuery(query url.Values, limits Limits) (transform, error) {
+	mode := query.Get("mode")
+	if mode == "" {
+		mode = modeFill
+	}
+	if mode != modeFill {
+		return transform{}, fmt.Errorf("%s: %w", ErrCanNotParseQuery, fmt.Errorf("mode %q is not supported", mode))
+	}
+
+	width, err := getWidth(query.Get("w"), limits.MinWidth, limits.MaxWidth)
+	if err != nil {
+		return transform{}, fmt.Errorf("%s: %w", ErrCanNotParseQuery, err)
+	}
+
+	height, err := getHeight(query.Get("h"), limits.MinHeight, limits.MaxHeight)
+	if err != nil {
+		return transform{}, fmt.Errorf("%s: %w", ErrCanNotParseQuery, err)
+	}
+
+	source, err := getURL(query.Get("url"))
+	if err != nil {
+		return transform{}, fmt.Errorf("%s: %w", ErrCanNotParseQuery, err)
+	}
+
+	return transform{mode: mode, width: width, height: height, source: source}, nil
+}
+
+// parsePathStyle returns transform from path like /fill/300/200/{URL} or /preset/thumb/{URL}.
+func (p *Previewer) parsePathStyle(path string, rawQuery string) (transform, error) {
+	if strings.HasPrefix(path, presetPathPrefix) {
+		return p.parsePreset(path, rawQuery)
+	}
+	if p.presetsOnly {
+		return transform{}, ErrRawDimensionsDisabled
+	}
+
+	return parseTransform(path, rawQuery, p.limits)
+}
+
+// parseQueryStyle returns transform from query like url={URL}&w=300&h=200 or url={URL}&preset=thumb.
+func (p *Previewer) parseQueryStyle(query url.Values) (transform, error) {
+	if name := query.Get("preset"); name != "" {
+		source, err := getURL(query.Get("url"))
+		if err != nil {
+			return transform{}, fmt.Errorf("%s: %w", ErrCanNotParseQuery, err)
+		}
+
+		return p.presetTransform(name, source)
+	}
+	if p.presetsOnly {
+		return transform{}, ErrRawDimensionsDisabled
+	}
+
+	return parseQuery(query, p.limits)
+}
+
+// parseTarget returns transform from request URI of any URL style, i.e. a line of warm-up list.
+func (p *Previewer) parseTarget(target string) (transform, error) {
+	u, err := url.ParseRequestURI(target)
+	if err != nil {
+		return transform{}, fmt.Errorf("%s: %w", ErrCanNotParsePath, err)
+	}
+	if u.Path == queryStylePath {
+		return p.parseQueryStyle(u.Query())
+	}
+
+	return p.parsePathStyle(u.Path, u.RawQuery)
+}
+
+// parseStatus returns response status for error of parsing transform.
+func parseStatus(err error) int {
+	if errors.Is(err, ErrRawDimensionsDisabled) {
+		return http.StatusForbidden
+	}
+
+	return http.StatusBadRequest
+}
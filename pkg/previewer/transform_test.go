@@ -0,0 +1,161 @@
+package previewer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuery(t *testing.T) {
+	limits := Limits{MinWidth: 50, MinHeight: 50, MaxWidth: 2000, MaxHeight: 2000}
+	fill := transform{mode: modeFill, width: 300, height: 200, source: "http://example.com/a.jpg?v=3"}
+
+	for _, tc := range []struct {
+		name     string
+		query    string
+		expected transform
+		err      string
+	}{
+		{"fill", "url=example.com%2Fa.jpg%3Fv%3D3&w=300&h=200&mode=fill", fill, ""},
+		{"default mode", "url=http%3A%2F%2Fexample.com%2Fa.jpg%3Fv%3D3&w=300&h=200", fill, ""},
+		{"uppercase scheme", "url=HTTP%3A%2F%2Fexample.com%2Fa.jpg%3Fv%3D3&w=300&h=200", fill, ""},
+		{"unknown mode", "url=example.com%2Fa.jpg&w=300&h=200&mode=fit", transform{},
+			`can not parse query: mode "fit" is not supported`},
+		{"no width", "url=example.com%2Fa.jpg&h=200", transform{}, "can not parse query: can not get width"},
+		{"height bounds", "url=example.com%2Fa.jpg&w=300&h=20", transform{},
+			"can not parse query: height value must be in range [50, 2000]"},
+		{"not jpeg", "url=example.com%2Fa.png&w=300&h=200", transform{},
+			"can not parse query: file extension must be jpg or jpeg"},
+		{"https", "url=https%3A%2F%2Fexample.com%2Fa.jpg&w=300&h=200", transform{},
+			"can not parse query: scheme https is not supported, source URL must be http"},
+		{"scheme in query", "url=example.com%2Fa.jpg%3Fback%3Dhttps%3A%2F%2Fexample.com&w=300&h=200",
+			transform{mode: modeFill, width: 300, height: 200, source: "http://example.com/a.jpg?back=https://example.com"},
+			""},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tc.query)
+			require.NoError(t, err)
+
+			actual, err := parseQuery(query, limits)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+
+	t.Run("query of path style", func(t *testing.T) {
+		fromPath, err := parseTransform("/fill/300/200/example.com/a.jpg", "v=3", limits)
+		require.NoError(t, err)
+		require.Equal(t, fill, fromPath)
+		require.Equal(t, "/fill/300/200/example.com/a.jpg?v=3", fromPath.key())
+	})
+
+	t.Run("same key as path", func(t *testing.T) {
+		query, err := url.ParseQuery("url=http%3A%2F%2Fexample.com%2Fa.jpg&w=300&h=200")
+		require.NoError(t, err)
+		fromQuery, err := parseQuery(query, limits)
+		require.NoError(t, err)
+
+		for _, path := range []string{
+			"/fill/300/200/example.com/a.jpg",
+			"/fill/300/200/http://example.com/a.jpg",
+			"/fill/300/200/HTTP://example.com/a.jpg",
+		} {
+			fromPath, err := parseTransform(path, "", limits)
+			require.NoError(t, err)
+			require.Equal(t, fromQuery, fromPath)
+			require.Equal(t, "/fill/300/200/example.com/a.jpg", fromPath.key())
+		}
+	})
+}
+
+func TestQueryStyle(t *testing.T) {
+	p := newTestPreviewer(t)
+	defer p.cache.Clear()
+	log.SetOutput(ioutil.Discard)
+
+	requests := 0
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		imageServerHandleFunc(w, r)
+	}))
+	defer imageServer.Close()
+
+	previewServer := httptest.NewServer(p)
+	defer previewServer.Close()
+
+	get := func(target string) []byte {
+		rs, err := http.Get(previewServer.URL + target) //nolint:noctx
+		require.NoError(t, err)
+		defer rs.Body.Close()
+		body, err := ioutil.ReadAll(rs.Body)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, rs.StatusCode, string(body))
+
+		return body
+	}
+
+	origin := strings.TrimPrefix(imageServer.URL, "http://")
+	fromQuery := get("/preview?" + url.Values{
+		"url": {imageServer.URL + "/images/source.jpg"},
+		"w":   {"50"},
+		"h":   {"60"},
+	}.Encode())
+	fromPath := get(fmt.Sprintf("/fill/50/60/%s/images/source.jpg", origin))
+	require.Equal(t, fromQuery, fromPath)
+	require.Equal(t, 1, requests) // the second preview is taken from cache
+
+	_, _, ok, err := p.cache.GetFile(fmt.Sprintf("/fill/50/60/%s/images/source.jpg", origin))
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	for _, query := range []string{"w=50&h=60", "url=https%3A%2F%2Fexample.com%2Fa.jpg&w=50&h=60"} {
+		rs, err := http.Get(previewServer.URL + "/preview?" + query) //nolint:noctx
+		require.NoError(t, err)
+		rs.Body.Close()
+		require.Equal(t, http.StatusBadRequest, rs.StatusCode, query)
+	}
+}
+
+func TestOriginQuery(t *testing.T) {
+	p := newTestPreviewer(t)
+	defer p.cache.Clear()
+	log.SetOutput(ioutil.Discard)
+
+	versions := []string{}
+	imageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		versions = append(versions, r.URL.RawQuery)
+		imageServerHandleFunc(w, r)
+	}))
+	defer imageServer.Close()
+
+	previewServer := httptest.NewServer(p)
+	defer previewServer.Close()
+
+	origin := strings.TrimPrefix(imageServer.URL, "http://")
+	for _, query := range []string{"v=3&token=abc", "v=4&token=abc", "v=3&token=abc"} {
+		rs, err := http.Get(fmt.Sprintf("%s/fill/50/50/%s/images/source.jpg?%s", previewServer.URL, origin, query)) //nolint:noctx
+		require.NoError(t, err)
+		rs.Body.Close()
+		require.Equal(t, http.StatusOK, rs.StatusCode)
+	}
+	require.Equal(t, []string{"v=3&token=abc", "v=4&token=abc"}, versions) // the third one is cached
+
+	for _, version := range []string{"3", "4"} {
+		_, _, ok, err := p.cache.GetFile(fmt.Sprintf("/fill/50/50/%s/images/source.jpg?v=%s&token=abc", origin, version))
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+}
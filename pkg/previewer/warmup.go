@@ -0,0 +1,147 @@
+package previewer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+const maxWarmUpBody = 1 << 20
+
+var (
+	ErrCanNotWarmUp          = errors.New("can not warm up cache")
+	ErrInvalidWarmUpSettings = errors.New("invalid warm-up settings")
+)
+
+type warmUpResult struct {
+	Path   string `json:"path"`
+	Status int    `json:"status"`
+	Cached bool   `json:"cached"` // preview was already in cache
+	Error  string `json:"error,omitempty"`
+}
+
+type warmUpResponse struct {
+	Total   int            `json:"total"`
+	Failed  int            `json:"failed"`
+	Results []warmUpResult `json:"results"`
+}
+
+// warmUp drives preview paths through the preview pipeline with at most concurrency previews at once,
+// so warm-up takes a bounded share of resources from normal requests.
+func (p *Previewer) warmUp(paths []string, concurrency int) []warmUpResult {
+	results := make([]warmUpResult, len(paths))
+	jobs := make(chan int)
+	wg := &sync.WaitGroup{}
+
+	for i := 0; i < concurrency && i < len(paths); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				t, err := p.parseTarget(paths[j])
+				if err != nil {
+					results[j] = warmUpResult{Path: paths[j], Status: parseStatus(err), Error: err.Error()}
+
+					continue
+				}
+				preview := p.makePreview(t, http.Header{}, true)
+				results[j] = warmUpResult{Path: paths[j], Status: preview.status, Cached: preview.cached}
+				if preview.err != nil {
+					results[j].Error = preview.err.Error()
+				}
+			}
+		}()
+	}
+
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// WarmUpFromFile renders previews which paths are listed in file, one per line. Results are logged only.
+func (p *Previewer) WarmUpFromFile(fileName string) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		p.logger.Println("[ERROR]", fmt.Errorf("%s: %w", ErrCanNotWarmUp, err))
+
+		return
+	}
+	defer f.Close()
+
+	paths, err := readPaths(f)
+	if err != nil {
+		p.logger.Println("[ERROR]", fmt.Errorf("%s: %w", ErrCanNotWarmUp, err))
+
+		return
+	}
+
+	p.logger.Printf("[INFO] warm-up started: %d previews from %s", len(paths), fileName)
+	failed := 0
+	for _, result := range p.warmUp(paths, p.warmUpConcurrency) {
+		if result.Error != "" {
+			failed++
+			p.logger.Printf("[WARN] warm-up of %s failed: %s", result.Path, result.Error)
+		}
+	}
+	p.logger.Printf("[INFO] warm-up finished: %d previews, %d failed", len(paths), failed)
+}
+
+// adminWarmUpHandler renders previews which paths are listed in request body, one per line:
+// POST /admin/warmup.
+func (p *Previewer) adminWarmUpHandler(w http.ResponseWriter, r *http.Request) {
+	fromHost := r.RemoteAddr
+	p.logger.Printf("[INFO] get admin request from %s; %s %s", fromHost, r.Method, r.URL.RequestURI())
+
+	if !p.authorize(w, r) {
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		p.sendResponse(w, http.StatusMethodNotAllowed, nil, fromHost, nil,
+			fmt.Errorf("method %s is not allowed", r.Method))
+
+		return
+	}
+
+	paths, err := readPaths(http.MaxBytesReader(w, r.Body, maxWarmUpBody))
+	if err != nil {
+		p.sendResponse(w, http.StatusBadRequest, nil, fromHost, nil, fmt.Errorf("%s: %w", ErrCanNotWarmUp, err))
+
+		return
+	}
+
+	response := warmUpResponse{Total: len(paths), Results: p.warmUp(paths, p.warmUpConcurrency)}
+	for _, result := range response.Results {
+		if result.Error != "" {
+			response.Failed++
+		}
+	}
+
+	p.logger.Printf("[INFO] warm-up finished: %d previews, %d failed", response.Total, response.Failed)
+	p.sendJSON(w, fromHost, response)
+}
+
+// readPaths returns non-empty lines, lines starting with # are comments.
+func readPaths(r io.Reader) ([]string, error) {
+	paths := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		paths = append(paths, line)
+	}
+
+	return paths, scanner.Err()
+}
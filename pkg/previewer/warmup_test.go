@@ -0,0 +1,78 @@
+package previewer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarmUp(t *testing.T) {
+	p := newTestPreviewer(t, WithAdminToken("secret"))
+	defer p.cache.Clear()
+	log.SetOutput(ioutil.Discard)
+
+	imageServer := httptest.NewServer(http.HandlerFunc(imageServerHandleFunc))
+	defer imageServer.Close()
+
+	source := imageServer.URL + "/images/source.jpg"
+	body := fmt.Sprintf("# previews for launch\n/fill/50/50/%[1]s\n\n/fill/100/100/%[1]s\n/fill/1/1/%[1]s\n", source)
+
+	t.Run("admin", func(t *testing.T) {
+		rq := httptest.NewRequest(http.MethodPost, "/admin/warmup", strings.NewReader(body))
+		rq.Header.Set("Authorization", "Bearer secret")
+		rw := httptest.NewRecorder()
+		p.adminWarmUpHandler(rw, rq)
+		require.Equal(t, http.StatusOK, rw.Code)
+
+		response := warmUpResponse{}
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &response))
+		require.Equal(t, 3, response.Total)
+		require.Equal(t, 1, response.Failed)
+		require.Equal(t, http.StatusOK, response.Results[0].Status)
+		require.Equal(t, http.StatusOK, response.Results[1].Status)
+		require.Equal(t, http.StatusBadRequest, response.Results[2].Status)
+		require.Contains(t, response.Results[2].Error, "width value must be in range")
+
+		_, _, ok, err := p.cache.GetFile("/fill/50/50/" + strings.TrimPrefix(source, "http://")) // canonical key
+		require.NoError(t, err)
+		require.True(t, ok)
+	})
+
+	t.Run("file", func(t *testing.T) {
+		f, err := ioutil.TempFile("", "warmup")
+		require.NoError(t, err)
+		defer os.Remove(f.Name())
+		_, err = f.WriteString("/fill/50/50/" + source + "\n/fill/60/60/" + source + "\n")
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		p.WarmUpFromFile(f.Name())
+
+		_, _, ok, err := p.cache.GetFile("/fill/60/60/" + strings.TrimPrefix(source, "http://"))
+		require.NoError(t, err)
+		require.True(t, ok)
+	})
+
+	t.Run("cached", func(t *testing.T) {
+		results := p.warmUp([]string{"/fill/60/60/" + source}, 1)
+		require.True(t, results[0].Cached)
+	})
+}
+
+func TestWarmUpConcurrency(t *testing.T) {
+	for _, concurrency := range []int{0, -1} {
+		_, err := New(WithWarmUpConcurrency(concurrency))
+		require.EqualError(t, err, "invalid warm-up settings: concurrency must be positive")
+	}
+
+	_, err := New(WithWarmUpConcurrency(1))
+	require.NoError(t, err)
+}
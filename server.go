@@ -11,37 +11,39 @@ import (
 	"os/signal"
 	"strconv"
 	"syscall"
+
+	internal_cache "github.com/sinuspower/image-previewer/internal/cache"
+	internal_settings "github.com/sinuspower/image-previewer/internal/settings"
+	"github.com/sinuspower/image-previewer/pkg/previewer"
 )
 
 type ProxyServer interface {
 	ListenAndServe() error
 }
 
+// Server runs previewer until it is stopped by signal and keeps or clears cache then.
 type Server struct {
-	port      int
-	cacheSize int
+	settings  *internal_settings.Settings
+	cache     internal_cache.Storage
+	previewer *previewer.Previewer
 	logOutput io.Writer
+	logger    *log.Logger // writes to logOutput, global logger is left to the caller
 	server    *http.Server
 }
 
-var (
-	ErrListenAndServe  = errors.New("error starting or closing listener")
-	ErrWritingResponse = errors.New("error writing response to client")
-	ErrCreateCutter    = errors.New("error during cutter creation")
-	ErrCanNotLoadImage = errors.New("can not load image from server")
-	ErrCanNotCutImage  = errors.New("can not cut image")
-)
-
-func NewServer(port int, cacheSize int, logOutput io.Writer) ProxyServer {
-	http.HandleFunc("/fill/", fillHandler)
-	log.SetOutput(logOutput)
+var ErrListenAndServe = errors.New("error starting or closing listener")
 
+func NewServer(settings *internal_settings.Settings, cache internal_cache.Storage,
+	p *previewer.Previewer, logOutput io.Writer) ProxyServer {
 	return &Server{
-		port:      port,
-		cacheSize: cacheSize,
+		settings:  settings,
+		cache:     cache,
+		previewer: p,
 		logOutput: logOutput,
+		logger:    log.New(logOutput, "", log.LstdFlags),
 		server: &http.Server{
-			Addr: ":" + strconv.Itoa(port),
+			Addr:    ":" + strconv.Itoa(settings.GetPort()),
+			Handler: p,
 		},
 	}
 }
@@ -55,104 +57,54 @@ func (s *Server) ListenAndServe() error {
 
 		<-done
 		if err := s.server.Shutdown(context.Background()); err != nil {
-			log.Printf("[ERROR] server shutdown error: %v", err)
+			s.logger.Printf("[ERROR] server shutdown error: %v", err)
 		}
 		close(idleConnsClosed)
 	}()
 
-	log.Printf("[INFO] listening port %d; cache size: %d images", s.port, s.cacheSize)
+	s.logger.Printf("[INFO] listening port %d; cache size: %d images", s.settings.GetPort(), s.settings.GetCacheSize())
 	fmt.Fprintln(s.logOutput)
+	if fileName := s.settings.GetWarmUpFile(); fileName != "" {
+		go s.previewer.WarmUpFromFile(fileName)
+	}
+	stopWatchdog := make(chan struct{})
+	go s.previewer.RunWatchdog(stopWatchdog)
+	defer close(stopWatchdog)
 	if err := s.server.ListenAndServe(); err != http.ErrServerClosed {
 		return fmt.Errorf("%s: %w", ErrListenAndServe, err)
 	}
 
 	<-idleConnsClosed
 	fmt.Fprintln(s.logOutput)
-	log.Println("[INFO] server stopped")
-	err := cache.Clear()
-	if err != nil {
-		log.Println("[WARN] can not clear cache")
-	} else {
-		log.Println("[INFO] cache cleared")
-	}
-
-	return nil
-}
-
-func fillHandler(w http.ResponseWriter, r *http.Request) {
-	fromHost := r.RemoteAddr
-	path := r.URL.Path
-	rqHeader := r.Header.Clone() // copy original request headers
-	var e error
-
-	log.Printf("[INFO] get request from %s; path: %s", fromHost, path)
-
-	cutter, err := NewCutter(path)
-	if err != nil {
-		e = fmt.Errorf("%s: %w", ErrCreateCutter, err)
-		sendResponse(w, 400, rqHeader, fromHost, nil, e)
-
-		return
-	}
-
-	// make response from cache if requested image is in cache
-	image, ok, err := cache.GetFile(path)
-	if err != nil {
-		log.Println("[WARN] can not get preview from cache:", err)
+	s.logger.Println("[INFO] server stopped")
+	if tiered, ok := s.cache.(internal_cache.Tiered); ok {
+		stats := tiered.TieredStats()
+		s.logger.Printf("[INFO] cache stats: hot tier %d hits, %d misses; cold tier %d hits, %d misses",
+			stats.Hot.Hits, stats.Hot.Misses, stats.Cold.Hits, stats.Cold.Misses)
 	}
-	if ok {
-		log.Println("[INFO] get preview from cache")
-		sendResponse(w, 200, rqHeader, fromHost, image, nil)
 
-		return
-	}
-
-	image, rsHeader, err := cutter.LoadImage(rqHeader)
-	if err != nil {
-		e = fmt.Errorf("%s: %w", ErrCanNotLoadImage, err)
-		sendResponse(w, 500, rsHeader, fromHost, nil, e)
+	if s.settings.GetCachePersistent() {
+		if tiered, ok := s.cache.(internal_cache.Tiered); ok {
+			if err := tiered.Flush(); err != nil {
+				s.logger.Println("[WARN] can not flush hot cache tier:", err)
+			}
+		}
+		s.logger.Println("[INFO] cache kept on disk")
 
-		return
+		return nil
 	}
+	if s.settings.GetCacheBackend() == internal_cache.BackendRedis { // shared by other replicas
+		s.logger.Println("[INFO] redis cache kept")
 
-	image, err = cutter.Cut(image)
-	if err != nil {
-		e = fmt.Errorf("%s: %w", ErrCanNotCutImage, err)
-		sendResponse(w, 500, rsHeader, fromHost, nil, e)
-
-		return
+		return nil
 	}
 
-	// put resized image into cache
-	err = cache.PutFile(path, image)
+	err := s.cache.Clear()
 	if err != nil {
-		log.Println("[WARN] can not put preview into cache:", err)
+		s.logger.Println("[WARN] can not clear cache")
 	} else {
-		log.Println("[INFO] put preview into cache")
-	}
-
-	sendResponse(w, 200, rsHeader, fromHost, image, nil)
-}
-
-func sendResponse(w http.ResponseWriter, status int, header http.Header, toHost string, data []byte, err error) {
-	// copy headers
-	for key, values := range header {
-		for _, value := range values {
-			w.Header().Add(key, value)
-		}
-	}
-	w.WriteHeader(status)
-	if err != nil {
-		log.Println("[ERROR]", err)
-		data = []byte(err.Error() + "\n")
+		s.logger.Println("[INFO] cache cleared")
 	}
 
-	written, err := w.Write(data)
-	if err != nil {
-		log.Println("[ERROR]", fmt.Errorf("%s: %w", ErrWritingResponse, err))
-
-		return
-	}
-
-	log.Printf("[INFO] send response to %s, %d bytes, status %d", toHost, written, status)
+	return nil
 }
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sinuspower/image-previewer/pkg/previewer"
+)
+
+var ErrCanNotSign = errors.New("can not sign URL")
+
+// runSign prints signed preview URLs: image-previewer sign [-key KEY] [-base URL] /fill/300/200/{URL}...
+// Key is the first one of IMAGE_PREVIEWER_SIGNING_KEYS by default.
+func runSign(args []string, output io.Writer) error {
+	flags := flag.NewFlagSet("sign", flag.ContinueOnError)
+	flags.SetOutput(output)
+	key := flags.String("key", firstSigningKey(), "signing key, the first of IMAGE_PREVIEWER_SIGNING_KEYS by default")
+	base := flags.String("base", "", "base URL of previewer prepended to signed path, i.e. http://localhost:8080")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%s: %w", ErrCanNotSign, err)
+	}
+
+	if *key == "" {
+		return fmt.Errorf("%s: %w", ErrCanNotSign, errors.New("signing key is not set"))
+	}
+	if flags.NArg() == 0 {
+		return fmt.Errorf("%s: %w", ErrCanNotSign, errors.New("no preview paths given"))
+	}
+
+	for _, target := range flags.Args() {
+		if !strings.HasPrefix(target, "/") {
+			return fmt.Errorf("%s: %w", ErrCanNotSign, fmt.Errorf("path %s must start with /", target))
+		}
+		fmt.Fprintln(output, strings.TrimSuffix(*base, "/")+previewer.SignURL(*key, target))
+	}
+
+	return nil
+}
+
+func firstSigningKey() string {
+	return strings.TrimSpace(strings.SplitN(os.Getenv("IMAGE_PREVIEWER_SIGNING_KEYS"), ",", 2)[0])
+}
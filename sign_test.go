@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/sinuspower/image-previewer/pkg/previewer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunSign(t *testing.T) {
+	os.Setenv("IMAGE_PREVIEWER_SIGNING_KEYS", "new-secret-key-0001,old-secret-key-0000")
+	defer os.Unsetenv("IMAGE_PREVIEWER_SIGNING_KEYS")
+
+	output := new(bytes.Buffer)
+	require.NoError(t, runSign([]string{"-base", "http://localhost:8080/", "/fill/300/200/example.com/a.jpg"}, output))
+	require.Equal(t, "http://localhost:8080"+previewer.SignURL("new-secret-key-0001", "/fill/300/200/example.com/a.jpg")+"\n",
+		output.String())
+
+	output.Reset()
+	require.NoError(t, runSign([]string{"-key", "old-secret-key-0000", "/fill/1/1/a.jpg", "/fill/2/2/b.jpg"}, output))
+	require.Equal(t, previewer.SignURL("old-secret-key-0000", "/fill/1/1/a.jpg")+"\n"+
+		previewer.SignURL("old-secret-key-0000", "/fill/2/2/b.jpg")+"\n", output.String())
+
+	require.EqualError(t, runSign(nil, output), "can not sign URL: no preview paths given")
+	require.EqualError(t, runSign([]string{"fill/1/1/a.jpg"}, output), "can not sign URL: path fill/1/1/a.jpg must start with /")
+
+	os.Unsetenv("IMAGE_PREVIEWER_SIGNING_KEYS")
+	require.EqualError(t, runSign([]string{"/fill/1/1/a.jpg"}, output), "can not sign URL: signing key is not set")
+}